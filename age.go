@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errSignatureTooOld = errors.New("signature too old")
+
+// maxAge is a flag value accepting durations such as 12h, 30d, or 1d12h.
+type maxAge time.Duration
+
+func (a *maxAge) String() string {
+	if *a == 0 {
+		return "0"
+	}
+	return formatAge(time.Duration(*a))
+}
+
+func (a *maxAge) Set(s string) error {
+	var d time.Duration
+	rest := s
+	if days, after, ok := strings.Cut(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid age %q", s)
+		}
+		d, rest = time.Duration(n)*24*time.Hour, after
+	}
+	if rest != "" {
+		rd, err := time.ParseDuration(rest)
+		if err != nil || rd < 0 {
+			return fmt.Errorf("invalid age %q", s)
+		}
+		d += rd
+	}
+	if d <= 0 {
+		return fmt.Errorf("invalid age %q", s)
+	}
+	*a = maxAge(d)
+	return nil
+}
+
+func (a *maxAge) Type() string {
+	return "age"
+}
+
+// formatAge formats d in days and hours when it's a day or more.
+func formatAge(d time.Duration) string {
+	if d < 24*time.Hour {
+		return d.Round(time.Second).String()
+	}
+	days := d / (24 * time.Hour)
+	hours := (d % (24 * time.Hour)) / time.Hour
+	if hours == 0 {
+		return fmt.Sprintf("%dd", days)
+	}
+	return fmt.Sprintf("%dd%dh", days, hours)
+}
+
+// signatureAge returns how long ago the signature file was last modified.
+// The modification time is set by whoever wrote the file, so it tells
+// nothing about when the signature was really made.
+func signatureAge(sigName string) (time.Duration, error) {
+	info, err := os.Stat(sigName)
+	if err != nil {
+		return 0, err
+	}
+	return max(time.Since(info.ModTime()), 0), nil
+}
+
+// checkSignatureAge fails if the signature file is older than limit.
+func checkSignatureAge(sigName string, limit maxAge) (time.Duration, error) {
+	age, err := signatureAge(sigName)
+	if err != nil {
+		return 0, fmt.Errorf("could not check the age of %s: %w", sigName, err)
+	}
+	if age > time.Duration(limit) {
+		return age, fmt.Errorf("%w: %s was modified %s ago, more than %s", errSignatureTooOld, sigName, formatAge(age), formatAge(time.Duration(limit)))
+	}
+	return age, nil
+}
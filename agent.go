@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// lockedKeyAttempts is how many times signing is attempted with
+// --retry-on-locked-key.
+const lockedKeyAttempts = 3
+
+var errKeyLocked = errors.New("the agent refused to sign, the key might require a confirmation (ssh-add -c) that was declined or timed out")
+
+// openAgentKey picks a key from the SSH agent listening on SSH_AUTH_SOCK.
+//
+// The returned function closes the connection to the agent, and must only be
+// called once signing is done.
+func openAgentKey(sel keySelector, p prompter) (ssh.Signer, func() error, error) {
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return nil, nil, fmt.Errorf("agent: SSH_AUTH_SOCK is not set")
+	}
+
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		return nil, nil, fmt.Errorf("agent: %w", err)
+	}
+
+	signers, err := agent.NewClient(conn).Signers()
+	if err != nil {
+		_ = conn.Close()
+		return nil, nil, fmt.Errorf("agent: %w", err)
+	}
+	if len(signers) == 0 {
+		_ = conn.Close()
+		return nil, nil, fmt.Errorf("agent: no keys loaded")
+	}
+
+	pubs := make([]ssh.PublicKey, 0, len(signers))
+	for _, signer := range signers {
+		pubs = append(pubs, signer.PublicKey())
+	}
+	i, err := selectKey("the SSH agent", pubs, sel, p)
+	if err != nil {
+		_ = conn.Close()
+		return nil, nil, err
+	}
+	return signers[i], conn.Close, nil
+}
+
+// openAgentPublicKeys lists the keys loaded in the SSH agent listening on
+// SSH_AUTH_SOCK.
+func openAgentPublicKeys() ([]*agent.Key, error) {
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return nil, fmt.Errorf("agent: SSH_AUTH_SOCK is not set")
+	}
+
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		return nil, fmt.Errorf("agent: %w", err)
+	}
+	defer conn.Close()
+
+	keys, err := agent.NewClient(conn).List()
+	if err != nil {
+		return nil, fmt.Errorf("agent: %w", err)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("agent: no keys loaded")
+	}
+	return keys, nil
+}
+
+// agentKeyName describes the agent key with the given fingerprint by its
+// comment and fingerprint.
+func agentKeyName(keys []*agent.Key, fingerprint string) string {
+	for _, key := range keys {
+		if ssh.FingerprintSHA256(key) == fingerprint {
+			return fmt.Sprintf("agent key %q %s", key.Comment, fingerprint)
+		}
+	}
+	return "agent key " + fingerprint
+}
+
+// isAgentRefusal reports whether the agent answered a sign request with a
+// failure, which is what happens when a confirmation is declined.
+func isAgentRefusal(err error) bool {
+	return err != nil && err.Error() == "agent: failed to sign challenge"
+}
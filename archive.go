@@ -0,0 +1,93 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+)
+
+var errMemberNotFound = errors.New("member not found in archive")
+
+// readArchiveMember reads a single member from a tar, tar.gz, or zip archive,
+// without extracting the rest of it.
+func readArchiveMember(archive, member string) ([]byte, error) {
+	f, err := os.Open(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	magic, err := r.Peek(4)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	member = path.Clean(member)
+	switch {
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")):
+		info, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		return readZipMember(f, info.Size(), member)
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		return readTarMember(gz, member)
+	default:
+		return readTarMember(r, member)
+	}
+}
+
+func readTarMember(r io.Reader, member string) ([]byte, error) {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%s: %w", member, errMemberNotFound)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid tar archive: %w", err)
+		}
+		if path.Clean(hdr.Name) != member {
+			continue
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			return nil, fmt.Errorf("%s: not a regular file", member)
+		}
+		return io.ReadAll(tr)
+	}
+}
+
+func readZipMember(r io.ReaderAt, size int64, member string) ([]byte, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("invalid zip archive: %w", err)
+	}
+	for _, f := range zr.File {
+		if path.Clean(f.Name) != member {
+			continue
+		}
+		if f.FileInfo().IsDir() {
+			return nil, fmt.Errorf("%s: not a regular file", member)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	return nil, fmt.Errorf("%s: %w", member, errMemberNotFound)
+}
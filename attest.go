@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// attestHeaderPrefix prefixes the PEM headers recording the attestation
+// fields, so verify can report mismatches precisely. The headers themselves
+// are not trusted: the fields are authenticated by the signature.
+const attestHeaderPrefix = "Attest-"
+
+var errAttestationMismatch = errors.New("attestations do not match")
+
+// parseAttestations parses key=value attestation fields.
+//
+// Keys and values cannot start or end with whitespace, which is trimmed from
+// the PEM headers they're recorded in, so they'd be reported differently
+// than signed.
+func parseAttestations(in []string) (map[string]string, error) {
+	fields := make(map[string]string, len(in))
+	for _, kv := range in {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid attestation %q, expected key=value", kv)
+		}
+		if strings.ContainsAny(k, ":\r\n") || strings.ContainsAny(v, "\r\n") {
+			return nil, fmt.Errorf("invalid attestation %q: keys cannot contain colons or newlines, values cannot contain newlines", kv)
+		}
+		if k != strings.TrimSpace(k) || v != strings.TrimSpace(v) {
+			return nil, fmt.Errorf("invalid attestation %q: keys and values cannot start or end with whitespace", kv)
+		}
+		if _, ok := fields[k]; ok {
+			return nil, fmt.Errorf("duplicated attestation %q", k)
+		}
+		fields[k] = v
+	}
+	return fields, nil
+}
+
+// attestedMessage frames the message together with the attestation fields,
+// sorted by key, so both are covered by the signature.
+func attestedMessage(message []byte, fields map[string]string) []byte {
+	var out []byte
+	out = appendFramed(out, []byte("ssign-attest-v1"))
+	out = appendFramed(out, message)
+	for _, k := range slices.Sorted(maps.Keys(fields)) {
+		out = appendFramed(out, []byte(k))
+		out = appendFramed(out, []byte(fields[k]))
+	}
+	return out
+}
+
+func appendFramed(out, b []byte) []byte {
+	out = binary.BigEndian.AppendUint32(out, uint32(len(b)))
+	return append(out, b...)
+}
+
+// withAttestationHeaders records the attestation fields as PEM headers of
+// the given signature.
+func withAttestationHeaders(sig []byte, fields map[string]string) ([]byte, error) {
+	block, _ := pem.Decode(sig)
+	if block == nil {
+		return nil, fmt.Errorf("invalid PEM signature")
+	}
+	block.Headers = make(map[string]string, len(fields))
+	for k, v := range fields {
+		block.Headers[attestHeaderPrefix+k] = v
+	}
+	return pem.EncodeToMemory(block), nil
+}
+
+// attestationHeaders returns the attestation fields recorded in the PEM
+// headers of the given signature.
+func attestationHeaders(sig []byte) map[string]string {
+	fields := map[string]string{}
+	block, _ := pem.Decode(sig)
+	if block == nil {
+		return fields
+	}
+	for k, v := range block.Headers {
+		if name, ok := strings.CutPrefix(k, attestHeaderPrefix); ok {
+			fields[name] = v
+		}
+	}
+	return fields
+}
+
+// diffAttestations describes every field that differs between the expected
+// attestations and the ones recorded in the signature.
+func diffAttestations(want, got map[string]string) error {
+	var diffs []string
+	for _, k := range slices.Sorted(maps.Keys(want)) {
+		v, ok := got[k]
+		switch {
+		case !ok:
+			diffs = append(diffs, fmt.Sprintf("%s: expected %q, missing from signature", k, want[k]))
+		case v != want[k]:
+			diffs = append(diffs, fmt.Sprintf("%s: expected %q, signature has %q", k, want[k], v))
+		}
+	}
+	for _, k := range slices.Sorted(maps.Keys(got)) {
+		if _, ok := want[k]; !ok {
+			diffs = append(diffs, fmt.Sprintf("%s: not expected, signature has %q", k, got[k]))
+		}
+	}
+	if len(diffs) > 0 {
+		return fmt.Errorf("%w: %s", errAttestationMismatch, strings.Join(diffs, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+var errBatchFailed = errors.New("verification failed")
+
+// batchResult is the outcome of verifying one file of a batch.
+type batchResult struct {
+	result
+	// Size is the number of bytes read from the file.
+	Size int64
+	// Elapsed is the time taken to verify the file.
+	Elapsed time.Duration
+	Err     error
+}
+
+// findSigned lists the files under dir which have a ".ssig" signature next to
+// them, or all the files when using extended attributes.
+func findSigned(dir string, xattr bool) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || strings.HasSuffix(path, ".ssig") {
+			return nil
+		}
+		if !xattr && !exists(path+".ssig") {
+			return nil
+		}
+		files = append(files, path)
+		return nil
+	})
+	return files, err
+}
+
+// verifyBatch verifies each of the given files against its signature. With
+// ReadAhead set, the next files are read in the background while the current
+// one is verified, so reading and verifying overlap.
+func (v verifier) verifyBatch(files []string, xattr bool, readBufferSize byteSize) []batchResult {
+	return v.verifyBatchFrom(files, xattr, func(file string) batchInput {
+		return readBatchInput(file, xattr, readBufferSize)
+	})
+}
+
+// verifyBatchFrom is [verifier.verifyBatch], reading the files with read.
+func (v verifier) verifyBatchFrom(files []string, xattr bool, read func(string) batchInput) []batchResult {
+	var inputs <-chan batchInput
+	if v.ReadAhead > 0 {
+		inputs = readAhead(files, v.ReadAhead, read)
+	}
+
+	results := make([]batchResult, 0, len(files))
+	for _, file := range files {
+		var in batchInput
+		if inputs != nil {
+			in = <-inputs
+		} else {
+			in = read(file)
+		}
+		start := time.Now()
+		fv := v
+		if v.Namespaces != nil {
+			fv.Namespace = v.Namespaces.namespace(file, v.Namespace)
+			fv.Policy.Namespace = fv.Namespace
+		}
+		res, err := fv.verifyInput(in, xattr)
+		if err != nil {
+			res = result{
+				File:        file,
+				Key:         v.KeyName,
+				Fingerprint: signerFingerprint(file, xattr),
+				Namespace:   fv.Namespace,
+			}
+		}
+		results = append(results, batchResult{result: res, Size: int64(len(in.message)), Elapsed: in.elapsed + time.Since(start), Err: err})
+	}
+	return results
+}
+
+// batchInput is a file of a batch, and its signature, read ahead of being
+// verified.
+type batchInput struct {
+	file      string
+	message   []byte
+	sigName   string
+	signature []byte
+	err       error
+	// elapsed is the time taken to read the file and its signature.
+	elapsed time.Duration
+}
+
+func readBatchInput(file string, xattr bool, readBufferSize byteSize) batchInput {
+	start := time.Now()
+	in := batchInput{file: file}
+	in.message, in.err = readFile(file, readBufferSize)
+	if in.err != nil {
+		in.err = fmt.Errorf("could not open subject: %w", in.err)
+	} else {
+		in.sigName, in.signature, in.err = readSignature(file, "", xattr)
+	}
+	in.elapsed = time.Since(start)
+	return in
+}
+
+// readAhead reads the files in order in the background, keeping up to n of
+// them in memory until they are received.
+func readAhead(files []string, n int, read func(string) batchInput) <-chan batchInput {
+	inputs := make(chan batchInput, n)
+	go func() {
+		defer close(inputs)
+		for _, file := range files {
+			inputs <- read(file)
+		}
+	}()
+	return inputs
+}
+
+func (v verifier) verifyInput(in batchInput, xattr bool) (result, error) {
+	if in.err != nil {
+		return result{}, in.err
+	}
+	res, err := v.verify(in.file, in.message, in.sigName, in.signature)
+	if err == nil && v.MaxSignatureAge > 0 && !xattr {
+		_, err = checkSignatureAge(in.sigName, v.MaxSignatureAge)
+	}
+	return res, err
+}
+
+// signerFingerprint returns the fingerprint of the key embedded in the
+// signature of file, or an empty string if the signature can't be read.
+func signerFingerprint(file string, xattr bool) string {
+	_, signature, err := readSignature(file, "", xattr)
+	if err != nil {
+		return ""
+	}
+	sig, err := parseSignature(signature)
+	if err != nil {
+		return ""
+	}
+	return ssh.FingerprintSHA256(sig.PublicKey)
+}
+
+// keyGroup is the outcome of verifying the files signed by one key.
+type keyGroup struct {
+	// Fingerprint is the fingerprint of the signing key, empty when the
+	// signature could not be read.
+	Fingerprint string `json:"fingerprint,omitempty"`
+	Files       int    `json:"files"`
+	Failed      int    `json:"failed"`
+}
+
+// groupByKey groups the results by the fingerprint of their signing key.
+func groupByKey(results []batchResult) []keyGroup {
+	var groups []keyGroup
+	index := map[string]int{}
+	for _, r := range results {
+		i, ok := index[r.Fingerprint]
+		if !ok {
+			i = len(groups)
+			index[r.Fingerprint] = i
+			groups = append(groups, keyGroup{Fingerprint: r.Fingerprint})
+		}
+		groups[i].Files++
+		if r.Err != nil {
+			groups[i].Failed++
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Fingerprint < groups[j].Fingerprint
+	})
+	return groups
+}
+
+// batchFailures counts the failed results.
+func batchFailures(results []batchResult) int {
+	var n int
+	for _, r := range results {
+		if r.Err != nil {
+			n++
+		}
+	}
+	return n
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// BenchmarkVerifyBatch compares verifying large files with and without
+// --read-ahead, read from disk, where they are most likely in the page cache
+// and so read faster than they are verified, and from a simulated slow
+// storage, e.g. a network file system, where reading takes as long as
+// verifying.
+func BenchmarkVerifyBatch(b *testing.B) {
+	const (
+		files    = 16
+		fileSize = 8 << 20
+		ns       = "ssign@becker.software"
+	)
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		b.Fatal(err)
+	}
+	key, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	dir := b.TempDir()
+	names := make([]string, 0, files)
+	content := make([]byte, fileSize)
+	for i := range files {
+		if _, err := rand.Read(content); err != nil {
+			b.Fatal(err)
+		}
+		name := filepath.Join(dir, fmt.Sprintf("file%02d.bin", i))
+		sig, err := signMessage(key, content, ns)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := os.WriteFile(name, content, 0o644); err != nil {
+			b.Fatal(err)
+		}
+		if err := os.WriteFile(name+".ssig", sig, 0o644); err != nil {
+			b.Fatal(err)
+		}
+		names = append(names, name)
+	}
+
+	disk := func(file string) batchInput {
+		return readBatchInput(file, false, defaultReadBufferSize)
+	}
+	slow := func(file string) batchInput {
+		time.Sleep(25 * time.Millisecond)
+		return disk(file)
+	}
+	for _, storage := range []struct {
+		name string
+		read func(string) batchInput
+	}{
+		{"disk", disk},
+		{"slow", slow},
+	} {
+		for _, n := range []int{0, 1, 4} {
+			b.Run(fmt.Sprintf("%s/read-ahead=%d", storage.name, n), func(b *testing.B) {
+				v := verifier{
+					Pubs:      []ssh.PublicKey{key.PublicKey()},
+					Namespace: ns,
+					ReadAhead: n,
+				}
+				b.SetBytes(files * fileSize)
+				for b.Loop() {
+					if failed := batchFailures(v.verifyBatchFrom(names, false, storage.read)); failed > 0 {
+						b.Fatalf("%d files failed to verify", failed)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+var errInvalidBundle = errors.New("invalid bundle")
+
+// bundle is what is needed to verify a file offline: the file, its
+// signature, and the public key, stored as "name", "name.ssig", and
+// "name.pub" in a tar.gz or zip archive.
+type bundle struct {
+	Name      string
+	Mode      fs.FileMode
+	ModTime   time.Time
+	Content   []byte
+	Signature []byte
+	// PublicKey is in the authorized_keys format, it's optional.
+	PublicKey []byte
+}
+
+// writeBundle writes the bundle in the given format: "tar", a gzipped tar
+// archive, or "zip". The file keeps its mode in both formats.
+func writeBundle(w io.Writer, b bundle, format string) error {
+	members := []struct {
+		name string
+		mode fs.FileMode
+		data []byte
+	}{
+		{b.Name, b.Mode, b.Content},
+		{b.Name + ".ssig", 0o644, b.Signature},
+		{b.Name + ".pub", 0o644, b.PublicKey},
+	}
+	if b.PublicKey == nil {
+		members = members[:2]
+	}
+
+	switch format {
+	case "tar":
+		gz := gzip.NewWriter(w)
+		tw := tar.NewWriter(gz)
+		for _, m := range members {
+			if err := tw.WriteHeader(&tar.Header{
+				Typeflag: tar.TypeReg,
+				Name:     m.name,
+				Mode:     int64(m.mode.Perm()),
+				Size:     int64(len(m.data)),
+				ModTime:  b.ModTime,
+				Format:   tar.FormatPAX,
+			}); err != nil {
+				return err
+			}
+			if _, err := tw.Write(m.data); err != nil {
+				return err
+			}
+		}
+		if err := tw.Close(); err != nil {
+			return err
+		}
+		return gz.Close()
+	case "zip":
+		zw := zip.NewWriter(w)
+		for _, m := range members {
+			hdr := &zip.FileHeader{
+				Name:     m.name,
+				Method:   zip.Deflate,
+				Modified: b.ModTime,
+			}
+			hdr.SetMode(m.mode.Perm())
+			f, err := zw.CreateHeader(hdr)
+			if err != nil {
+				return err
+			}
+			if _, err := f.Write(m.data); err != nil {
+				return err
+			}
+		}
+		return zw.Close()
+	default:
+		return fmt.Errorf("invalid bundle format %q, expected tar or zip", format)
+	}
+}
+
+// readBundle reads a bundle written by [writeBundle], detecting its format.
+func readBundle(name string) (bundle, error) {
+	files, err := readArchiveFiles(name)
+	if err != nil {
+		return bundle{}, err
+	}
+
+	var b bundle
+	for member := range files {
+		base, ok := strings.CutSuffix(member, ".ssig")
+		if !ok {
+			continue
+		}
+		if _, ok := files[base]; !ok {
+			continue
+		}
+		if b.Name != "" {
+			return bundle{}, fmt.Errorf("%w: more than one signed file", errInvalidBundle)
+		}
+		b.Name = base
+	}
+	if b.Name == "" {
+		return bundle{}, fmt.Errorf("%w: no file with a .ssig signature", errInvalidBundle)
+	}
+	b.Content = files[b.Name]
+	b.Signature = files[b.Name+".ssig"]
+	b.PublicKey = files[b.Name+".pub"]
+	return b, nil
+}
+
+// readArchiveFiles reads all the regular files of a tar, tar.gz, or zip
+// archive, by their cleaned path.
+func readArchiveFiles(name string) (map[string][]byte, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	magic, err := r.Peek(4)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	files := map[string][]byte{}
+	switch {
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")):
+		info, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		zr, err := zip.NewReader(f, info.Size())
+		if err != nil {
+			return nil, fmt.Errorf("invalid zip archive: %w", err)
+		}
+		for _, zf := range zr.File {
+			if !zf.Mode().IsRegular() {
+				continue
+			}
+			rc, err := zf.Open()
+			if err != nil {
+				return nil, err
+			}
+			data, err := io.ReadAll(rc)
+			_ = rc.Close()
+			if err != nil {
+				return nil, err
+			}
+			files[path.Clean(zf.Name)] = data
+		}
+		return files, nil
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		return files, readTarFiles(gz, files)
+	default:
+		return files, readTarFiles(r, files)
+	}
+}
+
+func readTarFiles(r io.Reader, files map[string][]byte) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid tar archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		files[path.Clean(hdr.Name)] = data
+	}
+}
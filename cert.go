@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+var errUntrustedCA = errors.New("untrusted certificate")
+
+// certInfo holds the details of an SSH certificate.
+type certInfo struct {
+	Type            string            `json:"type"`
+	KeyID           string            `json:"key_id"`
+	Serial          uint64            `json:"serial"`
+	KeyType         string            `json:"key_type"`
+	KeyFingerprint  string            `json:"key_fingerprint"`
+	Principals      []string          `json:"principals"`
+	ValidAfter      string            `json:"valid_after"`
+	ValidBefore     string            `json:"valid_before"`
+	CriticalOptions map[string]string `json:"critical_options"`
+	Extensions      map[string]string `json:"extensions"`
+	CAKeyType       string            `json:"ca_key_type"`
+	CAFingerprint   string            `json:"ca_fingerprint"`
+}
+
+// openCertificate reads an SSH certificate in the authorized_keys format.
+func openCertificate(name string) (*ssh.Certificate, error) {
+	pub, err := openPublicKey(name)
+	if err != nil {
+		return nil, err
+	}
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("not a certificate: %s", pub.Type())
+	}
+	return cert, nil
+}
+
+func newCertInfo(cert *ssh.Certificate) certInfo {
+	certType := "user"
+	if cert.CertType == ssh.HostCert {
+		certType = "host"
+	}
+	return certInfo{
+		Type:            certType,
+		KeyID:           cert.KeyId,
+		Serial:          cert.Serial,
+		KeyType:         cert.Key.Type(),
+		KeyFingerprint:  ssh.FingerprintSHA256(cert.Key),
+		Principals:      cert.ValidPrincipals,
+		ValidAfter:      certTime(cert.ValidAfter),
+		ValidBefore:     certTime(cert.ValidBefore),
+		CriticalOptions: cert.CriticalOptions,
+		Extensions:      cert.Extensions,
+		CAKeyType:       cert.SignatureKey.Type(),
+		CAFingerprint:   ssh.FingerprintSHA256(cert.SignatureKey),
+	}
+}
+
+func certTime(t uint64) string {
+	switch t {
+	case 0:
+		return "always"
+	case ssh.CertTimeInfinity:
+		return "forever"
+	default:
+		return time.Unix(int64(t), 0).UTC().Format(time.RFC3339)
+	}
+}
+
+// lines returns the certificate details as label and value pairs.
+func (c certInfo) lines() [][2]string {
+	lines := [][2]string{
+		{"Type", c.Type},
+		{"Key ID", c.KeyID},
+		{"Serial", fmt.Sprintf("%d", c.Serial)},
+		{"Key", c.KeyType + " " + c.KeyFingerprint},
+		{"Valid after", c.ValidAfter},
+		{"Valid before", c.ValidBefore},
+		{"CA", c.CAKeyType + " " + c.CAFingerprint},
+	}
+	for _, p := range c.Principals {
+		lines = append(lines, [2]string{"Principal", p})
+	}
+	for _, k := range slices.Sorted(maps.Keys(c.CriticalOptions)) {
+		lines = append(lines, [2]string{"Critical option", optionString(k, c.CriticalOptions[k])})
+	}
+	for _, k := range slices.Sorted(maps.Keys(c.Extensions)) {
+		lines = append(lines, [2]string{"Extension", optionString(k, c.Extensions[k])})
+	}
+	return lines
+}
+
+func optionString(k, v string) string {
+	if v == "" {
+		return k
+	}
+	return k + "=" + v
+}
+
+// caMatch is the CA a certificate was issued by, and the principal it was
+// accepted for.
+type caMatch struct {
+	Cert      *ssh.Certificate
+	CA        string
+	Principal string
+}
+
+// checkCertificate checks that pub is a user certificate issued by one of
+// the given CAs, valid at the given time, and for principal, if set, or
+// else for any of its principals.
+func checkCertificate(pub ssh.PublicKey, cas []ssh.PublicKey, principal string, now time.Time) (caMatch, error) {
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return caMatch{}, fmt.Errorf("%w: the signing key is a %s key, not a certificate", errUntrustedCA, pub.Type())
+	}
+	if cert.CertType != ssh.UserCert {
+		return caMatch{}, fmt.Errorf("%w: %q is a host certificate", errUntrustedCA, cert.KeyId)
+	}
+	ca := ssh.FingerprintSHA256(cert.SignatureKey)
+	if !slices.ContainsFunc(cas, func(k ssh.PublicKey) bool { return ssh.FingerprintSHA256(k) == ca }) {
+		return caMatch{}, fmt.Errorf("%w: %q was issued by %s, which is not in the bundle", errUntrustedCA, cert.KeyId, ca)
+	}
+
+	if principal == "" && len(cert.ValidPrincipals) > 0 {
+		principal = cert.ValidPrincipals[0]
+	}
+	checker := ssh.CertChecker{Clock: func() time.Time { return now }}
+	if err := checker.CheckCert(principal, cert); err != nil {
+		return caMatch{}, fmt.Errorf("%w: %q: %w", errUntrustedCA, cert.KeyId, err)
+	}
+	return caMatch{Cert: cert, CA: ca, Principal: principal}, nil
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// combinedMessage returns what --combine signs for the given files, in order:
+// each file's size, as a 64-bit big endian integer, followed by its content.
+// The sizes frame the files, so bytes moved from one file to the next change
+// the message.
+func combinedMessage(files []string, size byteSize) ([]byte, error) {
+	var message []byte
+	for _, file := range files {
+		content, err := readFile(file, size)
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s: %w", file, err)
+		}
+		message = binary.BigEndian.AppendUint64(message, uint64(len(content)))
+		message = append(message, content...)
+	}
+	return message, nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// comparison is how two signatures relate, as shown by compare.
+type comparison struct {
+	A                      signatureInfo `json:"a"`
+	B                      signatureInfo `json:"b"`
+	SameKey                bool          `json:"same_key"`
+	SameNamespace          bool          `json:"same_namespace"`
+	SameHashAlgorithm      bool          `json:"same_hash_algorithm"`
+	SameSignatureAlgorithm bool          `json:"same_signature_algorithm"`
+	SameReserved           bool          `json:"same_reserved"`
+	// SameContent is nil when it can't be told from the signatures alone.
+	SameContent *bool  `json:"same_content"`
+	Content     string `json:"content"`
+	Equivalent  bool   `json:"equivalent"`
+}
+
+// deterministicAlgorithms are the signature algorithms that always give the
+// same signature for the same key and signed data: Ed25519, and RSA with
+// PKCS #1 v1.5 padding. ECDSA, and security keys, which sign a counter too,
+// don't.
+var deterministicAlgorithms = map[string]bool{
+	ssh.KeyAlgoED25519:   true,
+	ssh.KeyAlgoRSA:       true,
+	ssh.KeyAlgoRSASHA256: true,
+	ssh.KeyAlgoRSASHA512: true,
+}
+
+// compareSignatures compares two signatures, without verifying them.
+//
+// The content a signature covers is not in it, only its hash is signed, so
+// whether both cover the same content can only be told when they were made
+// by the same key, with the same namespace, hash algorithm, and reserved
+// field, using a deterministic algorithm: they are then identical if and
+// only if the content is the same.
+func compareSignatures(a, b *signature, nameA, nameB string) comparison {
+	c := comparison{
+		A:                      newSignatureInfo(nameA, a),
+		B:                      newSignatureInfo(nameB, b),
+		SameKey:                bytes.Equal(a.PublicKey.Marshal(), b.PublicKey.Marshal()),
+		SameNamespace:          a.Namespace == b.Namespace,
+		SameHashAlgorithm:      a.HashAlgorithm == b.HashAlgorithm,
+		SameSignatureAlgorithm: a.Signature.Format == b.Signature.Format,
+		SameReserved:           a.Reserved == b.Reserved,
+	}
+	switch {
+	case !c.SameKey || !c.SameNamespace || !c.SameHashAlgorithm || !c.SameSignatureAlgorithm || !c.SameReserved:
+		c.Content = "unknown, the signatures differ in more than the content"
+	case !deterministicAlgorithms[a.Signature.Format]:
+		c.Content = fmt.Sprintf("unknown, %s signatures differ even for the same content", a.Signature.Format)
+	default:
+		same := bytes.Equal(a.Signature.Blob, b.Signature.Blob) && bytes.Equal(a.Signature.Rest, b.Signature.Rest)
+		c.SameContent = &same
+		c.Content = "different"
+		if same {
+			c.Content = "same"
+		}
+	}
+	c.Equivalent = c.SameContent != nil && *c.SameContent
+	return c
+}
+
+// lines returns what differs, or not, between the signatures.
+func (c comparison) lines() [][2]string {
+	lines := [][2]string{
+		{"Key", sameOr(c.SameKey, c.A.KeyFingerprint, c.B.KeyFingerprint)},
+		{"Namespace", sameOr(c.SameNamespace, c.A.Namespace, c.B.Namespace)},
+		{"Hash algorithm", sameOr(c.SameHashAlgorithm, c.A.HashAlgorithm, c.B.HashAlgorithm)},
+		{"Signature algorithm", sameOr(c.SameSignatureAlgorithm, c.A.SignatureAlgorithm, c.B.SignatureAlgorithm)},
+	}
+	if !c.SameReserved || c.A.Reserved != "" {
+		lines = append(lines, [2]string{"Reserved", sameOr(c.SameReserved, fmt.Sprintf("%q", c.A.Reserved), fmt.Sprintf("%q", c.B.Reserved))})
+	}
+	return append(lines, [2]string{"Content", c.Content})
+}
+
+func sameOr(same bool, a, b string) string {
+	if same {
+		return "same, " + a
+	}
+	return "different, " + a + " and " + b
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"slices"
+
+	"github.com/spf13/cobra"
+)
+
+// transformFlags are the flags of sign and verify which transform the
+// content before it's signed or verified.
+var transformFlags = []string{
+	"strip-bom",
+	"canonicalize-newlines-to-crlf",
+	"normalize-unicode",
+	"json-canonical",
+	"jcs",
+	"json-pointer",
+}
+
+// subjectFlags are the flags of verify which take the content from
+// elsewhere than the file argument.
+var subjectFlags = []string{
+	"in",
+	"git-range",
+	"git-object",
+	"message-string",
+	"s3",
+	"gcs",
+}
+
+// flagConflicts lists, for each flag of sign or verify, the flags it can't
+// be used with. Both commands share the list, so a flag they both have has
+// the same conflicts in both.
+//
+// Only conflicts between flags belong here: the ones depending on the
+// arguments or on the values of the flags are checked when running.
+var flagConflicts = []struct {
+	flag   string
+	others []string
+}{
+	{"jcs", []string{"json-canonical", "json-pointer"}},
+	{"stdout-signature-only", []string{"xattr", "stdin-name", "tee", "command", "git-range", "git-object", "json", "template"}},
+	{"out", []string{"stdout-signature-only", "xattr", "sig", "combine", "continue-from", "ephemeral-key", "receipt", "git-range", "git-object", "command"}},
+	{"two-phase", []string{"tee", "command", "continue-from"}},
+	{"key-id", []string{"continue-from"}},
+	{"sig", []string{"command"}},
+	{"ephemeral-key", []string{"agent", "key", "xattr", "stdout-signature-only"}},
+	{"emit-fingerprint-file", []string{"git-range", "git-object"}},
+	{"both", []string{"xattr", "stdout-signature-only", "tee", "command", "git-range", "git-object", "combine", "continue-from"}},
+	{"receipt", []string{"xattr", "stdout-signature-only", "tee", "command", "by-content-hash-name", "bundle", "use-sidecar", "filter-command", "in", "git-range", "git-object", "message-string", "s3", "gcs"}},
+	{"combine", slices.Concat([]string{"xattr", "stdout-signature-only", "stdin-name", "tee", "command", "continue-from", "receipt", "emit-fingerprint-file", "by-content-hash-name", "manifest", "manifest-signature-only", "bundle", "use-sidecar", "filter-command", "base", "patch"}, subjectFlags, transformFlags)},
+	{"continue-from", slices.Concat([]string{"xattr", "stdout-signature-only", "stdin-name", "tee", "command", "git-range", "git-object", "attest", "receipt", "emit-fingerprint-file", "ephemeral-key", "json", "template"}, transformFlags)},
+	{"command", slices.Concat([]string{"xattr", "stdin-name", "git-range", "git-object", "attest"}, transformFlags)},
+	{"tee", slices.Concat([]string{"stdin-name", "git-range", "git-object", "attest"}, transformFlags)},
+	{"git-range", []string{"git-object", "xattr"}},
+	{"git-object", []string{"xattr", "stdin-name"}},
+	{"reproducible-tar", slices.Concat([]string{"xattr", "stdin-name", "tee", "command", "continue-from", "combine", "receipt", "both", "bundle", "inline", "sig-db", "use-sidecar", "manifest", "manifest-signature-only", "by-content-hash-name", "emit-fingerprint-file", "use-fingerprint-file", "filter-command", "ref", "path", "base", "patch"}, subjectFlags, transformFlags)},
+
+	// verify.
+	{"quiet-on-success-only", []string{"json", "template", "output"}},
+	{"dry-verify", []string{"template", "output", "metrics", "quiet-on-success-only", "explain", "compat-keygen-verify"}},
+	{"silent", []string{"quiet-on-success-only", "json", "template", "output", "confirm-fingerprint"}},
+	{"max-signature-age", []string{"xattr", "bundle"}},
+	{"sbom", []string{"agent", "public-key", "pkcs11", "vault-key-path", "ca-bundle", "known-hosts", "bundle", "receipt", "confirm-fingerprint", "trust-embedded-key", "honor-key-expiry"}},
+	{"ca-bundle", []string{"agent", "public-key", "pkcs11", "vault-key-path", "known-hosts", "confirm-fingerprint", "trust-embedded-key", "honor-key-expiry"}},
+	{"agent", []string{"public-key", "pkcs11", "vault-key-path", "known-hosts", "confirm-fingerprint", "trust-embedded-key"}},
+	{"vault-key-path", []string{"public-key", "pkcs11", "known-hosts", "confirm-fingerprint", "trust-embedded-key"}},
+	{"use-fingerprint-file", slices.Concat([]string{"fingerprint-file"}, subjectFlags)},
+	{"use-sidecar", slices.Concat([]string{"xattr", "by-content-hash-name", "bundle", "base", "patch"}, subjectFlags)},
+	{"bundle", slices.Concat([]string{"xattr", "by-content-hash-name", "manifest", "manifest-signature-only", "filter-command"}, subjectFlags)},
+	{"filter-command", slices.Concat([]string{"manifest", "manifest-signature-only"}, subjectFlags)},
+	{"manifest", subjectFlags},
+	{"manifest-signature-only", subjectFlags},
+	{"pin-from-filename", []string{"xattr", "by-content-hash-name", "receipt", "bundle", "use-sidecar", "inline", "sig-db"}},
+	{"inline", slices.Concat([]string{"xattr", "by-content-hash-name", "manifest", "manifest-signature-only", "receipt", "bundle", "use-sidecar", "combine", "sig-db", "filter-command", "base", "patch"}, subjectFlags)},
+	{"sig-db", []string{"xattr", "by-content-hash-name", "manifest", "manifest-signature-only", "receipt", "bundle", "use-sidecar", "combine"}},
+	{"base", slices.Concat([]string{"xattr", "by-content-hash-name", "manifest", "manifest-signature-only", "receipt", "bundle", "filter-command"}, subjectFlags)},
+	{"patch", slices.Concat([]string{"xattr", "by-content-hash-name", "manifest", "manifest-signature-only", "receipt", "bundle", "filter-command"}, subjectFlags)},
+	{"message-string", []string{"in", "git-range", "git-object", "xattr"}},
+	{"s3", []string{"gcs", "in", "git-range", "git-object", "xattr"}},
+	{"gcs", []string{"in", "git-range", "git-object", "xattr"}},
+	{"in", []string{"git-range", "git-object", "xattr"}},
+	{"ref", gitRefConflicts},
+	{"path", gitRefConflicts},
+}
+
+// gitRefConflicts are the flags which --ref and --path, reading the file from
+// a git repository, can't be used with.
+var gitRefConflicts = slices.Concat([]string{
+	"xattr",
+	"combine",
+	"receipt",
+	"bundle",
+	"inline",
+	"sig-db",
+	"use-sidecar",
+	"manifest",
+	"manifest-signature-only",
+	"by-content-hash-name",
+	"use-fingerprint-file",
+	"filter-command",
+	"base",
+	"patch",
+	"reproducible-tar",
+}, subjectFlags)
+
+// flagsTogether lists the flags of verify which must be used together.
+var flagsTogether = [][]string{
+	{"ref", "path"},
+	{"base", "patch"},
+	{"sbom", "component"},
+	{"known-hosts", "host"},
+	{"sig-db", "sig-key"},
+}
+
+// markFlagConflicts marks the flags of [flagConflicts] and [flagsTogether],
+// leaving out the ones the command doesn't have.
+func markFlagConflicts(cmd *cobra.Command) {
+	for _, c := range flagConflicts {
+		if !hasFlag(cmd, c.flag) {
+			continue
+		}
+		for _, other := range c.others {
+			if hasFlag(cmd, other) {
+				cmd.MarkFlagsMutuallyExclusive(c.flag, other)
+			}
+		}
+	}
+	for _, flags := range flagsTogether {
+		if !slices.ContainsFunc(flags, func(name string) bool { return !hasFlag(cmd, name) }) {
+			cmd.MarkFlagsRequiredTogether(flags...)
+		}
+	}
+}
+
+func hasFlag(cmd *cobra.Command, name string) bool {
+	return cmd.Flags().Lookup(name) != nil || cmd.PersistentFlags().Lookup(name) != nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// curveSupport is whether keys of a type, and curve, can sign and verify in
+// this build.
+type curveSupport struct {
+	KeyType string `json:"key_type"`
+	Curve   string `json:"curve,omitempty"`
+	Sign    bool   `json:"sign"`
+	Verify  bool   `json:"verify"`
+	Error   string `json:"error,omitempty"`
+}
+
+// keyProbes generate a throwaway key of each type ssign could be given.
+// P-224 is there to show keys on other curves are rejected.
+var keyProbes = []struct {
+	keyType  string
+	curve    string
+	generate func() (crypto.Signer, error)
+}{
+	{ssh.KeyAlgoED25519, "", func() (crypto.Signer, error) {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	}},
+	{ssh.KeyAlgoECDSA256, "P-256", ecdsaProbe(elliptic.P256())},
+	{ssh.KeyAlgoECDSA384, "P-384", ecdsaProbe(elliptic.P384())},
+	{ssh.KeyAlgoECDSA521, "P-521", ecdsaProbe(elliptic.P521())},
+	{"ecdsa", "P-224", ecdsaProbe(elliptic.P224())},
+	{ssh.KeyAlgoRSA, "", func() (crypto.Signer, error) {
+		return rsa.GenerateKey(rand.Reader, 2048)
+	}},
+}
+
+func ecdsaProbe(curve elliptic.Curve) func() (crypto.Signer, error) {
+	return func() (crypto.Signer, error) {
+		return ecdsa.GenerateKey(curve, rand.Reader)
+	}
+}
+
+// supportedCurves signs and verifies a message with a throwaway key of each
+// type, going through the same code as sign and verify do.
+func supportedCurves() []curveSupport {
+	support := make([]curveSupport, 0, len(keyProbes))
+	for _, probe := range keyProbes {
+		s := curveSupport{KeyType: probe.keyType, Curve: probe.curve}
+		if key, err := probe.generate(); err != nil {
+			s.Error = err.Error()
+		} else {
+			s = probeKey(s, key)
+		}
+		support = append(support, s)
+	}
+	return support
+}
+
+func probeKey(s curveSupport, key crypto.Signer) curveSupport {
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		s.Error = err.Error()
+		return s
+	}
+	message := []byte("ssign probe")
+	signature, err := signMessage(signer, message, defaultNamespace)
+	if err != nil {
+		s.Error = err.Error()
+		return s
+	}
+	s.Sign = true
+	if err := verifyMessage(signer.PublicKey(), message, signature, defaultNamespace); err != nil {
+		s.Error = err.Error()
+		return s
+	}
+	s.Verify = true
+	return s
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
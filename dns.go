@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// dnsFingerprintPrefix prefixes the TXT records publishing a signer's key
+// fingerprint, e.g. "ssign=SHA256:...".
+const dnsFingerprintPrefix = "ssign="
+
+// checkDNSIdentity checks that the fingerprint of the given key is published in
+// the TXT records of the given name.
+//
+// The system resolver is used, and ssign does not validate DNSSEC itself.
+func checkDNSIdentity(name string, pub ssh.PublicKey) error {
+	records, err := net.LookupTXT(name)
+	if err != nil {
+		return err
+	}
+
+	fingerprint := ssh.FingerprintSHA256(pub)
+	var found bool
+	for _, record := range records {
+		fp, ok := strings.CutPrefix(strings.TrimSpace(record), dnsFingerprintPrefix)
+		if !ok {
+			continue
+		}
+		found = true
+		if strings.TrimSpace(fp) == fingerprint {
+			return nil
+		}
+	}
+	if !found {
+		return fmt.Errorf("no %q TXT records found", dnsFingerprintPrefix)
+	}
+	return fmt.Errorf("key %s is not published", fingerprint)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// plan returns what verifying subject against the signature at sigName
+// would check, as a result without a fingerprint, as nothing is verified.
+func (v verifier) plan(subject, sigName string) result {
+	key := v.KeyName
+	if len(v.Pubs) == 0 {
+		key = "embedded in " + sigName
+	}
+	return result{
+		File:      subject,
+		Signature: sigName,
+		Key:       key,
+		Namespace: v.Namespace,
+	}
+}
+
+// planBatch returns the [verifier.plan] of each file of a batch, without
+// reading them or their signatures.
+func (v verifier) planBatch(files []string, xattr bool) []result {
+	plans := make([]result, 0, len(files))
+	for _, file := range files {
+		fv := v
+		if v.Namespaces != nil {
+			fv.Namespace = v.Namespaces.namespace(file, v.Namespace)
+		}
+		sigName := file + ".ssig"
+		if xattr {
+			sigName = "xattr " + signatureXattr
+		}
+		plans = append(plans, fv.plan(file, sigName))
+	}
+	return plans
+}
+
+// printPlans prints what would be verified, for --dry-verify.
+func printPlans(cmd *cobra.Command, plans []result) {
+	styles := mustStyles()
+	cmd.Println(styles.Header.String())
+	for _, p := range plans {
+		cmd.Println(styles.Text.Render(
+			"Would verify " +
+				styles.Code.Render(p.File) +
+				" against " +
+				styles.Code.Render(p.Signature) +
+				" with key " +
+				styles.Code.Render(p.Key) +
+				" in namespace " +
+				styles.Code.Render(p.Namespace) +
+				".",
+		))
+	}
+}
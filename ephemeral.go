@@ -0,0 +1,22 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// newEphemeralKey generates an Ed25519 key which only lives in memory, for
+// one-shot signatures.
+//
+// Anyone can generate such a key, so its signatures only prove that the file
+// did not change since it was signed, and only to verifiers that got the
+// public key through a channel they trust.
+func newEphemeralKey() (ssh.Signer, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return ssh.NewSignerFromKey(priv)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+)
+
+// errorCode is a stable identifier for a class of failures, meant for scripts
+// handling ssign errors.
+type errorCode string
+
+const (
+	codeUnknown          errorCode = "ERR_UNKNOWN"
+	codeIO               errorCode = "ERR_IO"
+	codeInput            errorCode = "ERR_INPUT"
+	codeKey              errorCode = "ERR_KEY"
+	codeAgentRefused     errorCode = "ERR_AGENT_REFUSED"
+	codeInvalidSignature errorCode = "ERR_PEM_INVALID"
+	codeKeyMismatch      errorCode = "ERR_KEY_MISMATCH"
+	codeNamespace        errorCode = "ERR_NAMESPACE"
+	codeAlgorithm        errorCode = "ERR_ALGORITHM"
+	codeKeyTooSmall      errorCode = "ERR_KEY_TOO_SMALL"
+	codeAttestation      errorCode = "ERR_ATTESTATION"
+	codeDNSIdentity      errorCode = "ERR_DNS_IDENTITY"
+	codeManifest         errorCode = "ERR_MANIFEST"
+	codeUntrustedKey     errorCode = "ERR_UNTRUSTED_KEY"
+	codeUnsafePath       errorCode = "ERR_UNSAFE_PATH"
+	codeNotSigned        errorCode = "ERR_UNSIGNED"
+	codeReceipt          errorCode = "ERR_RECEIPT"
+	codeSignatureAge     errorCode = "ERR_SIGNATURE_AGE"
+	codeKeyExpired       errorCode = "ERR_KEY_EXPIRED"
+	codePatch            errorCode = "ERR_PATCH"
+	codeReserved         errorCode = "ERR_RESERVED"
+	codeSBOM             errorCode = "ERR_SBOM"
+	codeSidecar          errorCode = "ERR_SIDECAR"
+	codePin              errorCode = "ERR_PIN"
+	codeCA               errorCode = "ERR_CA"
+)
+
+// errorCatalog lists all error codes, as shown by --list-errors.
+var errorCatalog = []struct {
+	Code        errorCode
+	Description string
+}{
+	{codeIO, "a file could not be read or written"},
+	{codeInput, "the input is not valid for the requested mode, e.g. not JSON with --json-canonical"},
+	{codeKey, "the private or public key could not be loaded"},
+	{codeAgentRefused, "the SSH agent refused to sign"},
+	{codeInvalidSignature, "the signature could not be decoded"},
+	{codeKeyMismatch, "the signature does not match the key and content"},
+	{codeNamespace, "the signature was made in another, or a deprecated, namespace"},
+	{codeAlgorithm, "the signature algorithm is not allowed"},
+	{codeKeyTooSmall, "the signing key is smaller than allowed"},
+	{codeAttestation, "the signed attestations do not match the expected ones"},
+	{codeDNSIdentity, "the key is not published by the DNS identity"},
+	{codeManifest, "the files do not match the signed manifest"},
+	{codeUntrustedKey, "the embedded key was not trusted"},
+	{codeUnsafePath, "the manifest lists a path outside of its directory"},
+	{codeNotSigned, "a file required to be signed has no signature"},
+	{codeReceipt, "the file or the signing key do not match the receipt"},
+	{codeSignatureAge, "the signature file was modified longer ago than allowed"},
+	{codeKeyExpired, "the key is past the expiry in its authorized_keys comment"},
+	{codePatch, "the --patch could not be applied to the --base file"},
+	{codeReserved, "the signature reserved field is not the expected one"},
+	{codeSBOM, "the signing key is not the one the SBOM declares for the component"},
+	{codeSidecar, "the signing key or namespace do not match the --use-sidecar metadata"},
+	{codePin, "the signing key is not the one pinned by the signature file name"},
+	{codeCA, "the signing certificate was not issued by a --ca-bundle CA, or is not valid"},
+	{codeUnknown, "any other failure"},
+}
+
+// codedError attaches an error code to an error, without changing its
+// message.
+type codedError struct {
+	code errorCode
+	err  error
+}
+
+func (e codedError) Error() string { return e.err.Error() }
+func (e codedError) Unwrap() error { return e.err }
+
+func withCode(code errorCode, err error) error {
+	return codedError{code: code, err: err}
+}
+
+// codeOf returns the error code of the given error.
+func codeOf(err error) errorCode {
+	var cerr codedError
+	var perr *fs.PathError
+	switch {
+	case errors.As(err, &cerr):
+		return cerr.code
+	case errors.Is(err, errNamespaceMismatch), errors.Is(err, errNamespaceDeprecated):
+		return codeNamespace
+	case errors.Is(err, errAlgorithmForbidden):
+		return codeAlgorithm
+	case errors.Is(err, errKeyTooSmall):
+		return codeKeyTooSmall
+	case errors.Is(err, errReservedMismatch):
+		return codeReserved
+	case errors.Is(err, errAttestationMismatch):
+		return codeAttestation
+	case errors.Is(err, errUntrustedKey):
+		return codeUntrustedKey
+	case errors.Is(err, errUnsafePath):
+		return codeUnsafePath
+	case errors.Is(err, errReceiptMismatch):
+		return codeReceipt
+	case errors.Is(err, errSBOMMismatch):
+		return codeSBOM
+	case errors.Is(err, errSidecarMismatch):
+		return codeSidecar
+	case errors.Is(err, errPinMismatch):
+		return codePin
+	case errors.Is(err, errUntrustedCA):
+		return codeCA
+	case errors.Is(err, errInvalidPatch):
+		return codePatch
+	case errors.Is(err, errKeyExpired):
+		return codeKeyExpired
+	case errors.Is(err, errSignatureTooOld):
+		return codeSignatureAge
+	case errors.Is(err, errNotSigned):
+		return codeNotSigned
+	case errors.Is(err, errManifestMismatch):
+		return codeManifest
+	case errors.Is(err, errKeyLocked):
+		return codeAgentRefused
+	case errors.Is(err, errInvalidSignature), errors.Is(err, errMalformedSignature):
+		return codeInvalidSignature
+	case errors.Is(err, errInvalidJSON), errors.Is(err, errMemberNotFound), errors.Is(err, errInvalidManifest), errors.Is(err, errInvalidBundle), errors.Is(err, errJSONPointer), errors.Is(err, errInvalidSBOM), errors.Is(err, errInvalidSidecar), errors.Is(err, errInvalidInline), errors.Is(err, errInvalidPin):
+		return codeInput
+	case errors.As(err, &perr), errors.Is(err, errXattrUnsupported):
+		return codeIO
+	default:
+		return codeUnknown
+	}
+}
+
+// verifyErrorCode returns the error code of an error from [sshsig.Verify],
+// whose errors are not typed, so only their messages tell them apart.
+//
+// The namespace and hash algorithm are checked before, by [verifyMessage],
+// so the errors left are either an invalid signature or a key mismatch.
+func verifyErrorCode(err error) errorCode {
+	if strings.HasPrefix(err.Error(), "invalid") {
+		return codeInvalidSignature
+	}
+	return codeKeyMismatch
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/caarlos0/sshsig"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestCodeOf(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := testPublicKey(t)
+	message := []byte("hello")
+	armored, err := sshsig.Sign(signer, rand.Reader, message, "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := parseSignature(armored)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	old := filepath.Join(dir, "old.ssig")
+	if err := os.WriteFile(old, armored, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(old, time.Time{}, time.Now().Add(-48*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	manifest := filepath.Join(dir, "MANIFEST")
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	in, err := os.Open(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = in.Close() })
+
+	for name, tt := range map[string]struct {
+		err  func() error
+		code errorCode
+	}{
+		"strict namespace": {
+			func() error { return policy{Namespace: "git", StrictNamespace: true}.check(sig) },
+			codeNamespace,
+		},
+		"deprecated namespace": {
+			func() error { return policy{DeprecatedNamespaces: []string{"file"}}.check(sig) },
+			codeNamespace,
+		},
+		"required hash": {
+			func() error { return policy{RequireHash: "sha256"}.check(sig) },
+			codeAlgorithm,
+		},
+		"key algorithm": {
+			func() error { return policy{KeyAlgorithm: ssh.KeyAlgoRSA}.check(sig) },
+			codeAlgorithm,
+		},
+		"reserved": {
+			func() error { return policy{Reserved: "x", CheckReserved: true}.check(sig) },
+			codeReserved,
+		},
+		"fingerprint file": {
+			func() error {
+				return checkFingerprintFile(result{File: "f", Fingerprint: ssh.FingerprintSHA256(signer.PublicKey())}, ssh.FingerprintSHA256(other), "f.fpr")
+			},
+			codePin,
+		},
+		"filename pin": {
+			func() error { _, err := filenamePin("f.ssig"); return err },
+			codeInput,
+		},
+		"key expiry": {
+			func() error {
+				return checkKeyExpiry(map[string]keyExpiry{"fp": {Date: "2020-01-01"}}, "fp", time.Now())
+			},
+			codeKeyExpired,
+		},
+		"untrusted key": {
+			func() error { return confirmKey(prompter{In: in, Out: in}, signer.PublicKey(), false) },
+			codeUntrustedKey,
+		},
+		"key mismatch": {
+			func() error { return verifyMessage(other, message, armored, "file") },
+			codeKeyMismatch,
+		},
+		"verify namespace": {
+			func() error { return verifyMessage(signer.PublicKey(), message, armored, "git") },
+			codeNamespace,
+		},
+		"invalid signature": {
+			func() error { return verifyMessage(signer.PublicKey(), message, []byte("garbage"), "file") },
+			codeInvalidSignature,
+		},
+		"attestation": {
+			func() error { return diffAttestations(map[string]string{"a": "1"}, map[string]string{"a": "2"}) },
+			codeAttestation,
+		},
+		"manifest": {
+			func() error {
+				return checkManifest(manifest, "", []manifestEntry{{Path: "a.txt", Digest: "00"}}, false)
+			},
+			codeManifest,
+		},
+		"unsafe path": {
+			func() error {
+				_, err := parseManifest([]byte("0000000000000000000000000000000000000000000000000000000000000000  ../a\n"))
+				return err
+			},
+			codeUnsafePath,
+		},
+		"signature age": {
+			func() error { _, err := checkSignatureAge(old, maxAge(time.Hour)); return err },
+			codeSignatureAge,
+		},
+		"io": {
+			func() error { _, err := os.Open(filepath.Join(dir, "missing")); return err },
+			codeIO,
+		},
+		"json": {
+			func() error { _, err := jcsJSON([]byte("{")); return err },
+			codeInput,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := tt.err()
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if code := codeOf(err); code != tt.code {
+				t.Errorf("expected %s, got %s: %v", tt.code, code, err)
+			}
+		})
+	}
+}
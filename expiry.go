@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+var errKeyExpired = errors.New("key expired")
+
+const keyExpiryPrefix = "expires="
+
+// keyExpiry is when a trusted key stops being valid, as given by an
+// "expires=2025-12-31" word in its authorized_keys comment.
+type keyExpiry struct {
+	// Date is the date, or time, as written in the comment.
+	Date string
+	// At is the first instant the key is expired: the day after a date,
+	// in UTC, as the key is still valid on the given day.
+	At time.Time
+}
+
+// parseKeyExpiry finds the expiry in an authorized_keys comment, accepting
+// a date (2006-01-02) or a RFC 3339 time.
+func parseKeyExpiry(comment string) (keyExpiry, bool, error) {
+	for _, word := range strings.Fields(comment) {
+		date, ok := strings.CutPrefix(word, keyExpiryPrefix)
+		if !ok {
+			continue
+		}
+		if t, err := time.Parse(time.DateOnly, date); err == nil {
+			return keyExpiry{Date: date, At: t.AddDate(0, 0, 1)}, true, nil
+		}
+		if t, err := time.Parse(time.RFC3339, date); err == nil {
+			return keyExpiry{Date: date, At: t}, true, nil
+		}
+		return keyExpiry{}, false, fmt.Errorf("invalid expiry %q, expected a date like 2025-12-31", date)
+	}
+	return keyExpiry{}, false, nil
+}
+
+// openKeyExpiries reads the expiries in the comments of the keys of an
+// authorized_keys file, by key fingerprint. Keys without one never expire.
+func openKeyExpiries(name string) (map[string]keyExpiry, error) {
+	in, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	expiries := map[string]keyExpiry{}
+	for rest := in; len(rest) > 0; {
+		var pub ssh.PublicKey
+		var comment string
+		pub, comment, _, rest, err = ssh.ParseAuthorizedKey(rest)
+		if err != nil {
+			break
+		}
+		exp, ok, err := parseKeyExpiry(comment)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", ssh.FingerprintSHA256(pub), err)
+		}
+		fp := ssh.FingerprintSHA256(pub)
+		if _, seen := expiries[fp]; ok && !seen {
+			expiries[fp] = exp
+		}
+	}
+	return expiries, nil
+}
+
+// checkKeyExpiry fails if the key with the given fingerprint is expired as
+// of now.
+func checkKeyExpiry(expiries map[string]keyExpiry, fingerprint string, now time.Time) error {
+	exp, ok := expiries[fingerprint]
+	if !ok || now.Before(exp.At) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s was only valid until %s", errKeyExpired, fingerprint, exp.Date)
+}
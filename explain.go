@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// explainStep is one of the checks of a verification, as shown by --explain.
+type explainStep struct {
+	Name string
+	// Err is the reason the step failed, if it did.
+	Err error
+	// Skipped is the reason the step did not run, if it did not.
+	Skipped string
+}
+
+// explain runs each of the checks of [verifier.verify] on its own, stopping
+// at the first one that fails, so it can be reported.
+func (v verifier) explain(message, sigData []byte) []explainStep {
+	var steps []explainStep
+	failed := false
+	step := func(name string, check func() error) {
+		if failed {
+			steps = append(steps, explainStep{Name: name, Skipped: "an earlier step failed"})
+			return
+		}
+		err := check()
+		failed = err != nil
+		steps = append(steps, explainStep{Name: name, Err: err})
+	}
+
+	if v.StripBOM {
+		message = stripBOM(message)
+	}
+	if v.CRLF {
+		message = crlfNewlines(message)
+	}
+	message = normalizeUnicode(message, v.UnicodeForm)
+	if v.JSONPointer != "" {
+		step("JSON pointer "+v.JSONPointer, func() error {
+			var err error
+			message, err = jsonPointerSubset(message, v.JSONPointer)
+			return err
+		})
+	} else if v.JSONCanonical {
+		step("canonical JSON", func() error {
+			var err error
+			message, err = canonicalJSON(message)
+			return err
+		})
+	} else if v.JCS {
+		step("RFC 8785 canonical JSON", func() error {
+			var err error
+			message, err = jcsJSON(message)
+			return err
+		})
+	}
+	step("attestations", func() error {
+		recorded := attestationHeaders(sigData)
+		if len(v.Attestations) == 0 && len(recorded) == 0 {
+			return nil
+		}
+		if err := diffAttestations(v.Attestations, recorded); err != nil {
+			return err
+		}
+		message = attestedMessage(message, v.Attestations)
+		return nil
+	})
+
+	var sig *signature
+	step("PEM decode", func() error {
+		var err error
+		sig, err = parseSignature(sigData)
+		return err
+	})
+	step("namespace match", func() error {
+		if sig.Namespace != v.Namespace {
+			return fmt.Errorf("signature namespace is %q, expected %q", sig.Namespace, v.Namespace)
+		}
+		return nil
+	})
+	step("key type match", func() error {
+		if len(v.Pubs) == 0 {
+			return nil
+		}
+		var types []string
+		for _, pub := range v.Pubs {
+			if pub.Type() == sig.PublicKey.Type() {
+				return nil
+			}
+			types = append(types, pub.Type())
+		}
+		return fmt.Errorf("signature was made with a %s key, trusted keys are %v", sig.PublicKey.Type(), types)
+	})
+	step("key match", func() error {
+		if len(v.Pubs) == 0 {
+			return nil
+		}
+		embedded := sig.PublicKey.Marshal()
+		if slices.ContainsFunc(v.Pubs, func(pub ssh.PublicKey) bool {
+			return bytes.Equal(pub.Marshal(), embedded)
+		}) {
+			return nil
+		}
+		return fmt.Errorf("signature was made with %s, which is not in %s", ssh.FingerprintSHA256(sig.PublicKey), v.KeyName)
+	})
+	step("policy", func() error {
+		return v.Policy.check(sig)
+	})
+	step("signature validity", func() error {
+		pubs := v.Pubs
+		if len(pubs) == 0 {
+			pubs = []ssh.PublicKey{sig.PublicKey}
+		}
+		_, _, err := verifyAny(pubs, message, sigData, v.Namespace)
+		return err
+	})
+	if v.DNSIdentity != "" {
+		step("DNS identity", func() error {
+			return checkDNSIdentity(v.DNSIdentity, sig.PublicKey)
+		})
+	}
+	steps = append(steps,
+		explainStep{Name: "expiry", Skipped: "SSHSIG signatures have no timestamp"},
+		explainStep{Name: "revocation", Skipped: "ssign does not support revocation"},
+	)
+	return steps
+}
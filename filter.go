@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// shellCommand returns a command running the given command line through the
+// shell: sh, or cmd on Windows.
+func shellCommand(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command)
+	}
+	return exec.Command("sh", "-c", command)
+}
+
+// filterFile runs the named file through the given command, e.g. "gzip -dc",
+// and returns what the command wrote to its output.
+func filterFile(name, command string) ([]byte, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var stdout, stderr bytes.Buffer
+	cmd := shellCommand(command)
+	cmd.Stdin = f
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("filter command %q: %w: %s", command, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"charm.land/huh/v2"
+	"golang.org/x/crypto/ssh"
+)
+
+var errKeyExists = errors.New("key already exists")
+
+// keygenOptions are the choices of "ssign keygen", either from its flags or
+// from its wizard.
+type keygenOptions struct {
+	Type    string
+	Bits    int
+	Path    string
+	Comment string
+	Encrypt bool
+}
+
+// keygenBits are the allowed sizes of each key type, the first one being the
+// default.
+var keygenBits = map[string][]int{
+	"ed25519": nil,
+	"ecdsa":   {256, 384, 521},
+	"rsa":     {3072, 4096, 2048},
+}
+
+// check validates the options, setting the default size of the key type.
+func (o *keygenOptions) check() error {
+	sizes, ok := keygenBits[o.Type]
+	if !ok {
+		return fmt.Errorf("invalid key type %q, expected ed25519, ecdsa, or rsa", o.Type)
+	}
+	switch {
+	case len(sizes) == 0 && o.Bits != 0:
+		return fmt.Errorf("--bits cannot be used with %s keys", o.Type)
+	case len(sizes) == 0:
+	case o.Bits == 0:
+		o.Bits = sizes[0]
+	case !slices.Contains(sizes, o.Bits):
+		return fmt.Errorf("invalid --bits %d for %s keys, expected one of %v", o.Bits, o.Type, sizes)
+	}
+	if o.Path == "" {
+		return fmt.Errorf("the key path cannot be empty")
+	}
+	return nil
+}
+
+// defaultKeyPath is where ssh-keygen stores keys of the given type by default.
+func defaultKeyPath(typ string) string {
+	return os.ExpandEnv("$HOME/.ssh/id_" + typ)
+}
+
+// defaultKeyComment is the comment ssh-keygen gives keys by default.
+func defaultKeyComment() string {
+	host, _ := os.Hostname()
+	return os.Getenv("USER") + "@" + host
+}
+
+// generateKey generates a private key of the type and size of the options.
+func (o keygenOptions) generateKey() (crypto.Signer, error) {
+	switch o.Type {
+	case "ed25519":
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	case "ecdsa":
+		curves := map[int]elliptic.Curve{256: elliptic.P256(), 384: elliptic.P384(), 521: elliptic.P521()}
+		return ecdsa.GenerateKey(curves[o.Bits], rand.Reader)
+	default:
+		return rsa.GenerateKey(rand.Reader, o.Bits)
+	}
+}
+
+// writeKeyPair generates a key, and writes it like ssh-keygen does: the
+// private key in the OpenSSH format, encrypted with the passphrase if any, at
+// the options path, and its public key at the path plus ".pub", neither of
+// which may exist unless force is set.
+func writeKeyPair(o keygenOptions, passphrase []byte, force bool) (ssh.PublicKey, error) {
+	if !force {
+		for _, name := range []string{o.Path, o.Path + ".pub"} {
+			if exists(name) {
+				return nil, fmt.Errorf("%w: %s, use --force to overwrite it", errKeyExists, name)
+			}
+		}
+	}
+
+	key, err := o.generateKey()
+	if err != nil {
+		return nil, fmt.Errorf("could not generate key: %w", err)
+	}
+	pub, err := ssh.NewPublicKey(key.Public())
+	if err != nil {
+		return nil, fmt.Errorf("could not generate key: %w", err)
+	}
+	var block *pem.Block
+	if len(passphrase) > 0 {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(key, o.Comment, passphrase)
+	} else {
+		block, err = ssh.MarshalPrivateKey(key, o.Comment)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not encode key: %w", err)
+	}
+
+	if dir := filepath.Dir(o.Path); !exists(dir) {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, fmt.Errorf("could not create %s: %w", dir, err)
+		}
+	}
+	if err := writeKeyFile(o.Path, pem.EncodeToMemory(block), 0o600, force); err != nil {
+		return nil, fmt.Errorf("could not write key %s: %w", o.Path, err)
+	}
+	authorized := ssh.MarshalAuthorizedKey(pub)
+	if o.Comment != "" {
+		authorized = append(authorized[:len(authorized)-1], []byte(" "+o.Comment+"\n")...)
+	}
+	if err := writeKeyFile(o.Path+".pub", authorized, 0o644, force); err != nil {
+		return nil, fmt.Errorf("could not write public key %s: %w", o.Path+".pub", err)
+	}
+	return pub, nil
+}
+
+// writeKeyFile writes data to the named file with the given permissions,
+// which must not exist unless force is set, in which case the permissions of
+// an existing file are set too.
+func writeKeyFile(name string, data []byte, perm os.FileMode, force bool) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(name, flags, perm)
+	if err != nil {
+		return err
+	}
+	if err := f.Chmod(perm); err != nil {
+		_ = f.Close()
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// keygenAnswers are what the "ssign keygen" wizard asked for.
+type keygenAnswers struct {
+	keygenOptions
+	Passphrase []byte
+	Overwrite  bool
+}
+
+// askKeygenOptions walks through the choices of "ssign keygen": the key type
+// and size, where to store it, its comment, whether to encrypt it and with
+// which passphrase, and whether to overwrite an existing key.
+//
+// It's a single form, the groups that don't apply being hidden, as keys
+// typed right after a form ends can be lost before the next one starts.
+func askKeygenOptions(p prompter) (keygenAnswers, error) {
+	var a keygenAnswers
+	a.Type = "ed25519"
+	a.Comment = defaultKeyComment()
+	ecdsaBits, rsaBits := keygenBits["ecdsa"][0], keygenBits["rsa"][0]
+	var pass string
+	path := func() string {
+		if a.Path == "" {
+			return defaultKeyPath(a.Type)
+		}
+		return a.Path
+	}
+
+	err := p.runGroups(
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("Key type").
+				Options(
+					huh.NewOption("Ed25519 (recommended)", "ed25519"),
+					huh.NewOption("ECDSA", "ecdsa"),
+					huh.NewOption("RSA", "rsa"),
+				).
+				Value(&a.Type),
+		),
+		huh.NewGroup(bitsSelect(keygenBits["ecdsa"], &ecdsaBits)).
+			WithHideFunc(func() bool { return a.Type != "ecdsa" }),
+		huh.NewGroup(bitsSelect(keygenBits["rsa"], &rsaBits)).
+			WithHideFunc(func() bool { return a.Type != "rsa" }),
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Where to store the key").
+				Description("The public key is stored next to it, with a .pub extension.").
+				PlaceholderFunc(func() string { return defaultKeyPath(a.Type) }, &a.Type).
+				Value(&a.Path),
+			huh.NewInput().
+				Title("Comment").
+				Value(&a.Comment),
+			huh.NewConfirm().
+				Title("Encrypt the key with a passphrase?").
+				Value(&a.Encrypt),
+		),
+		newPassphraseGroup(&pass).
+			WithHideFunc(func() bool { return !a.Encrypt }),
+		huh.NewGroup(
+			huh.NewConfirm().
+				TitleFunc(func() string { return path() + " already exists, overwrite it?" }, &a.Path).
+				Value(&a.Overwrite),
+		).WithHideFunc(func() bool { return !exists(path()) && !exists(path()+".pub") }),
+	)
+	if err != nil {
+		return a, fmt.Errorf("could not ask for the key options: %w", err)
+	}
+
+	a.Path = path()
+	switch a.Type {
+	case "ecdsa":
+		a.Bits = ecdsaBits
+	case "rsa":
+		a.Bits = rsaBits
+	}
+	if a.Encrypt {
+		a.Passphrase = []byte(pass)
+	}
+	return a, nil
+}
+
+// bitsSelect selects one of the given key sizes.
+func bitsSelect(sizes []int, bits *int) *huh.Select[int] {
+	options := make([]huh.Option[int], 0, len(sizes))
+	for _, size := range sizes {
+		options = append(options, huh.NewOption(strconv.Itoa(size)+" bits", size))
+	}
+	return huh.NewSelect[int]().
+		Title("Key size").
+		Options(options...).
+		Value(bits)
+}
+
+// newPassphraseGroup asks for the passphrase of a new key, twice.
+func newPassphraseGroup(pass *string) *huh.Group {
+	var again string
+	return huh.NewGroup(
+		huh.NewInput().
+			Title("Passphrase").
+			EchoMode(huh.EchoModePassword).
+			Validate(func(s string) error {
+				if s == "" {
+					return errors.New("the passphrase cannot be empty")
+				}
+				return nil
+			}).
+			Value(pass),
+		huh.NewInput().
+			Title("Same passphrase again").
+			EchoMode(huh.EchoModePassword).
+			Validate(func(s string) error {
+				if s != *pass {
+					return errors.New("the passphrases do not match")
+				}
+				return nil
+			}).
+			Value(&again),
+	)
+}
+
+// askNewPassphrase asks for the passphrase of a new key, twice.
+func askNewPassphrase(p prompter) ([]byte, error) {
+	if p.PassphraseCommand != "" {
+		return p.passphrase()
+	}
+	var pass string
+	if err := p.runGroups(newPassphraseGroup(&pass)); err != nil {
+		return nil, fmt.Errorf("could not ask for the passphrase: %w", err)
+	}
+	return []byte(pass), nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+var (
+	errInvalidGitRange  = errors.New("invalid git range")
+	errInvalidGitObject = errors.New("invalid git object")
+)
+
+// gitDiffFlags pin every option that changes the generated diff, so it is the
+// same regardless of the git configuration of whoever generates it.
+var gitDiffFlags = []string{
+	"--no-color",
+	"--no-ext-diff",
+	"--no-textconv",
+	"--no-renames",
+	"--no-relative",
+	"--full-index",
+	"--binary",
+	"--unified=3",
+	"--diff-algorithm=myers",
+	"--indent-heuristic",
+	"--src-prefix=a/",
+	"--dst-prefix=b/",
+	"-O" + os.DevNull,
+}
+
+// gitConfig overrides the settings of the repository configuration that
+// change the generated diffs and patches, and have no command line option.
+var gitConfig = []string{
+	"core.quotePath=true",
+	"core.abbrev=auto",
+	"diff.noprefix=false",
+	"diff.mnemonicPrefix=false",
+	"diff.suppressBlankEmpty=false",
+	"diff.submodule=short",
+	"diff.interHunkContext=0",
+	"format.signature=",
+	"format.numbered=auto",
+	"format.subjectPrefix=PATCH",
+	"format.thread=false",
+	"format.coverLetter=false",
+	"format.signOff=false",
+	"format.notes=false",
+	"format.forceInBodyFrom=false",
+	"format.useAutoBase=false",
+	"format.mboxrd=false",
+	"format.encodeEmailHeaders=true",
+	"format.from=false",
+	"i18n.logOutputEncoding=UTF-8",
+	"log.mailmap=false",
+	"log.showSignature=false",
+}
+
+// gitRangeContent generates the content signed for a git range, in the
+// current repository, either as the output of "git format-patch" (format
+// "patch"), which includes the commit messages, or "git diff" (format
+// "diff").
+//
+// Generating the same content again requires the same commits, and a git
+// version that produces the same diffs, which is why the diff options and
+// the user and system configurations are ignored, and the settings of the
+// repository configuration which change them are overridden.
+func gitRangeContent(rng, format string) ([]byte, error) {
+	if !strings.Contains(rng, "..") || strings.HasPrefix(rng, "-") {
+		return nil, fmt.Errorf("%w: %q, expected from..to", errInvalidGitRange, rng)
+	}
+
+	var args []string
+	switch format {
+	case "patch":
+		args = append([]string{"format-patch", "--stdout", "--no-signature", "--no-stat", "--no-to", "--no-cc", "--no-add-header", "--no-attach"}, gitDiffFlags...)
+	case "diff":
+		args = append([]string{"diff"}, gitDiffFlags...)
+	default:
+		return nil, fmt.Errorf("invalid format %q, expected patch or diff", format)
+	}
+	return git(append(args, rng, "--")...)
+}
+
+// gitObjectContent reads a blob from the object store of the repository in
+// dir, or the current one if empty, returning its full object id and its
+// content.
+//
+// The object can be given as anything git resolves to a blob, such as an
+// abbreviated object id or "v1.0:README.md", but only blobs are accepted:
+// the signed content is then exactly the file as stored by git.
+func gitObjectContent(dir, object string) (string, []byte, error) {
+	if object == "" || strings.HasPrefix(object, "-") {
+		return "", nil, fmt.Errorf("%w: %q", errInvalidGitObject, object)
+	}
+	git := func(args ...string) ([]byte, error) { return gitIn(dir, args...) }
+	out, err := git("rev-parse", "--verify", "--quiet", "--end-of-options", object)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %q is not in this repository", errInvalidGitObject, object)
+	}
+	oid := strings.TrimSpace(string(out))
+	// "object^{blob}" would be simpler, but it's not parsed as such when the
+	// object is a path, like "v1.0:README.md".
+	typ, err := git("cat-file", "-t", oid)
+	if err != nil {
+		return "", nil, err
+	}
+	if t := strings.TrimSpace(string(typ)); t != "blob" {
+		return "", nil, fmt.Errorf("%w: %q is a %s, not a blob", errInvalidGitObject, object, t)
+	}
+	content, err := git("cat-file", "blob", oid)
+	if err != nil {
+		return "", nil, err
+	}
+	return oid, content, nil
+}
+
+// gitFileAtRef reads the file at path, relative to the root of the
+// repository in dir, as it was committed at ref, returning its blob id and
+// its content.
+func gitFileAtRef(dir, ref, path string) (string, []byte, error) {
+	if ref == "" || strings.HasPrefix(ref, "-") {
+		return "", nil, fmt.Errorf("%w: %q", errInvalidGitObject, ref)
+	}
+	if _, err := gitIn(dir, "rev-parse", "--verify", "--quiet", "--end-of-options", ref+"^{commit}"); err != nil {
+		return "", nil, fmt.Errorf("%w: no commit %q in %s", errInvalidGitObject, ref, dir)
+	}
+	if _, err := gitIn(dir, "rev-parse", "--verify", "--quiet", "--end-of-options", ref+":"+path); err != nil {
+		return "", nil, fmt.Errorf("%w: %s does not exist at %s", errInvalidGitObject, path, ref)
+	}
+	return gitObjectContent(dir, ref+":"+path)
+}
+
+// git runs git with the given arguments, ignoring the user and system
+// configurations and overriding [gitConfig], and returns its output.
+func git(args ...string) ([]byte, error) {
+	return gitIn("", args...)
+}
+
+// gitIn runs git like [git] in the given directory, or the current one if
+// empty.
+func gitIn(dir string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	var config []string
+	for _, c := range gitConfig {
+		config = append(config, "-c", c)
+	}
+	cmd := exec.Command("git", append(config, args...)...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_CONFIG_NOSYSTEM=1", "GIT_CONFIG_GLOBAL="+os.DevNull)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
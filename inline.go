@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+var errInvalidInline = errors.New("invalid inline signature")
+
+// inlineSuffix is appended to a file name to name its inline signed copy.
+const inlineSuffix = ".signed"
+
+var inlineSignatureStart = []byte("-----BEGIN " + signaturePEMType + "-----")
+
+// inlineSigned returns the content followed by its PEM signature, in a single
+// file, separated by a newline so the content can be split back exactly.
+func inlineSigned(content, signature []byte) []byte {
+	out := make([]byte, 0, len(content)+1+len(signature))
+	out = append(out, content...)
+	out = append(out, '\n')
+	return append(out, signature...)
+}
+
+// splitInline splits a file written by [inlineSigned] into its content and
+// signature, which starts at the last signature header.
+func splitInline(data []byte) ([]byte, []byte, error) {
+	i := bytes.LastIndex(data, inlineSignatureStart)
+	if i < 1 || data[i-1] != '\n' {
+		return nil, nil, fmt.Errorf("%w: no signature after the content", errInvalidInline)
+	}
+	return data[:i-1], data[i:], nil
+}
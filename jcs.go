@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// jcsJSON returns the RFC 8785 JSON Canonicalization Scheme form of the given
+// JSON document, which other JCS implementations produce too, unlike
+// [canonicalJSON].
+//
+// The document must be I-JSON (RFC 7493), as JCS requires: valid UTF-8, no
+// unpaired surrogate escapes, no duplicate object members, and numbers that
+// fit a float64.
+func jcsJSON(in []byte) ([]byte, error) {
+	if !utf8.Valid(in) {
+		return nil, fmt.Errorf("%w: not valid UTF-8", errInvalidJSON)
+	}
+	if err := checkSurrogates(in); err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidJSON, err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(in))
+	dec.UseNumber()
+
+	var out bytes.Buffer
+	if err := writeJCSValue(&out, dec); err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidJSON, err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("%w: unexpected data after top-level value", errInvalidJSON)
+	}
+	return out.Bytes(), nil
+}
+
+// checkSurrogates fails on the \u escapes of UTF-16 surrogates which are not
+// a high one followed by a low one, which encoding/json replaces with U+FFFD.
+func checkSurrogates(in []byte) error {
+	inString := false
+	for i := 0; i < len(in); i++ {
+		switch {
+		case in[i] == '"':
+			inString = !inString
+		case in[i] == '\\' && inString:
+			r := escapedRune(in[i:])
+			switch {
+			case r >= 0xd800 && r < 0xdc00:
+				if low := escapedRune(in[i+6:]); low < 0xdc00 || low > 0xdfff {
+					return fmt.Errorf("unpaired surrogate %s", in[i:i+6])
+				}
+				i += 11
+			case r >= 0xdc00 && r <= 0xdfff:
+				return fmt.Errorf("unpaired surrogate %s", in[i:i+6])
+			default:
+				// skips the escaped character, which might be a quote.
+				i++
+			}
+		}
+	}
+	return nil
+}
+
+// escapedRune returns the rune of the \uXXXX escape the input starts with, or
+// -1 if it doesn't start with one.
+func escapedRune(in []byte) rune {
+	if len(in) < 6 || in[0] != '\\' || in[1] != 'u' {
+		return -1
+	}
+	n, err := strconv.ParseUint(string(in[2:6]), 16, 16)
+	if err != nil {
+		return -1
+	}
+	return rune(n)
+}
+
+// writeJCSValue writes the next value of the decoder in its canonical form.
+func writeJCSValue(out *bytes.Buffer, dec *json.Decoder) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok == '[' {
+			return writeJCSArray(out, dec)
+		}
+		return writeJCSObject(out, dec)
+	case string:
+		writeJCSString(out, tok)
+	case json.Number:
+		f, err := strconv.ParseFloat(string(tok), 64)
+		if err != nil || math.IsInf(f, 0) {
+			return fmt.Errorf("number %s does not fit a float64", tok)
+		}
+		out.WriteString(jcsNumber(f))
+	case bool:
+		out.WriteString(strconv.FormatBool(tok))
+	case nil:
+		out.WriteString("null")
+	}
+	return nil
+}
+
+func writeJCSArray(out *bytes.Buffer, dec *json.Decoder) error {
+	out.WriteByte('[')
+	for i := 0; dec.More(); i++ {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		if err := writeJCSValue(out, dec); err != nil {
+			return err
+		}
+	}
+	out.WriteByte(']')
+	_, err := dec.Token()
+	return err
+}
+
+// writeJCSObject writes the members of an object sorted by the UTF-16 code
+// units of their names, as JavaScript sorts strings.
+func writeJCSObject(out *bytes.Buffer, dec *json.Decoder) error {
+	type member struct {
+		name  string
+		key   []uint16
+		value []byte
+	}
+	var members []member
+	seen := map[string]bool{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		name := tok.(string)
+		if seen[name] {
+			return fmt.Errorf("duplicate member %q", name)
+		}
+		seen[name] = true
+		var value bytes.Buffer
+		if err := writeJCSValue(&value, dec); err != nil {
+			return err
+		}
+		members = append(members, member{name, utf16.Encode([]rune(name)), value.Bytes()})
+	}
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+
+	slices.SortFunc(members, func(a, b member) int {
+		return slices.Compare(a.key, b.key)
+	})
+	out.WriteByte('{')
+	for i, m := range members {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		writeJCSString(out, m.name)
+		out.WriteByte(':')
+		out.Write(m.value)
+	}
+	out.WriteByte('}')
+	return nil
+}
+
+// writeJCSString writes the string escaping only what JSON requires, with
+// the short escapes where they exist.
+func writeJCSString(out *bytes.Buffer, s string) {
+	out.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			out.WriteString(`\"`)
+		case '\\':
+			out.WriteString(`\\`)
+		case '\b':
+			out.WriteString(`\b`)
+		case '\f':
+			out.WriteString(`\f`)
+		case '\n':
+			out.WriteString(`\n`)
+		case '\r':
+			out.WriteString(`\r`)
+		case '\t':
+			out.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(out, `\u%04x`, r)
+			} else {
+				out.WriteRune(r)
+			}
+		}
+	}
+	out.WriteByte('"')
+}
+
+// jcsNumber formats the number as JavaScript's Number.prototype.toString
+// does, which JCS requires: the shortest digits that parse back to the same
+// number, in plain notation unless its exponent is below -6 or above 20.
+func jcsNumber(f float64) string {
+	if f == 0 {
+		// including -0.
+		return "0"
+	}
+	sign := ""
+	if f < 0 {
+		sign, f = "-", -f
+	}
+	// d.ddde±x, the value is then 0.dddd×10^n.
+	mantissa, exp, _ := strings.Cut(strconv.FormatFloat(f, 'e', -1, 64), "e")
+	digits := strings.Replace(mantissa, ".", "", 1)
+	x, _ := strconv.Atoi(exp)
+	return formatNumber(sign, digits, x+1)
+}
+
+// formatNumber formats the number sign0.digits×10^n as JavaScript does, the
+// digits having no leading or trailing zeros.
+func formatNumber(sign, digits string, n int) string {
+	k := len(digits)
+	switch {
+	case k <= n && n <= 21:
+		return sign + digits + strings.Repeat("0", n-k)
+	case 0 < n && n <= 21:
+		return sign + digits[:n] + "." + digits[n:]
+	case -6 < n && n <= 0:
+		return sign + "0." + strings.Repeat("0", -n) + digits
+	}
+	e := "e" + strconv.Itoa(n-1)
+	if n-1 >= 0 {
+		e = "e+" + strconv.Itoa(n-1)
+	}
+	if k == 1 {
+		return sign + digits + e
+	}
+	return sign + digits[:1] + "." + digits[1:] + e
+}
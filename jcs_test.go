@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// The IEEE 754 numbers of RFC 8785 appendix B, and their serialization.
+func TestJCSNumber(t *testing.T) {
+	for _, tt := range []struct {
+		bits uint64
+		want string
+	}{
+		{0x0000000000000000, "0"},
+		{0x8000000000000000, "0"},
+		{0x0000000000000001, "5e-324"},
+		{0x8000000000000001, "-5e-324"},
+		{0x7fefffffffffffff, "1.7976931348623157e+308"},
+		{0xffefffffffffffff, "-1.7976931348623157e+308"},
+		{0x4340000000000000, "9007199254740992"},
+		{0xc340000000000000, "-9007199254740992"},
+		{0x4430000000000000, "295147905179352830000"},
+		{0x44b52d02c7e14af5, "9.999999999999997e+22"},
+		{0x44b52d02c7e14af6, "1e+23"},
+		{0x44b52d02c7e14af7, "1.0000000000000001e+23"},
+		{0x444b1ae4d6e2ef4e, "999999999999999700000"},
+		{0x444b1ae4d6e2ef4f, "999999999999999900000"},
+		{0x444b1ae4d6e2ef50, "1e+21"},
+		{0x3eb0c6f7a0b5ed8c, "9.999999999999997e-7"},
+		{0x3eb0c6f7a0b5ed8d, "0.000001"},
+		{0x41b3de4355555553, "333333333.3333332"},
+		{0x41b3de4355555554, "333333333.33333325"},
+		{0x41b3de4355555555, "333333333.3333333"},
+		{0x41b3de4355555556, "333333333.3333334"},
+		{0x41b3de4355555557, "333333333.33333343"},
+		{0xbecbf647612f3696, "-0.0000033333333333333333"},
+		{0x43143ff3c1cb0959, "1424953923781206.2"},
+	} {
+		if got := jcsNumber(math.Float64frombits(tt.bits)); got != tt.want {
+			t.Errorf("%016x: expected %s, got %s", tt.bits, tt.want, got)
+		}
+	}
+}
+
+func TestJCSJSON(t *testing.T) {
+	for name, tt := range map[string]struct {
+		in, want string
+	}{
+		"numbers": {
+			`[1e21, -0, 5e-324, 1.7976931348623157e308, 333333333.3333333, 1E30, 4.50, 2e-3, 1.0, 1e0]`,
+			`[1e+21,0,5e-324,1.7976931348623157e+308,333333333.3333333,1e+30,4.5,0.002,1,1]`,
+		},
+		// RFC 8785 section 3.2.2.
+		"sample": {
+			`{
+  "numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+  "string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+  "literals": [null, true, false]
+}`,
+			`{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`,
+		},
+		// RFC 8785 section 3.2.3, members sorted by their UTF-16 code units.
+		"sorting": {
+			`{
+  "\u20ac": "Euro Sign",
+  "\r": "Carriage Return",
+  "\ufb33": "Hebrew Letter Dalet With Dagesh",
+  "1": "One",
+  "\ud83d\ude00": "Emoji: Grinning Face",
+  "\u0080": "Control",
+  "\u00f6": "Latin Small Letter O With Diaeresis"
+}`,
+			"{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\",\"\u00f6\":\"Latin Small Letter O With Diaeresis\",\"\u20ac\":\"Euro Sign\",\"\U0001F600\":\"Emoji: Grinning Face\",\"\ufb33\":\"Hebrew Letter Dalet With Dagesh\"}",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := jcsJSON([]byte(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestJCSJSONInvalid(t *testing.T) {
+	for name, in := range map[string]string{
+		"empty":            ``,
+		"not json":         `nope`,
+		"unterminated":     `{"a":1`,
+		"trailing comma":   `[1,]`,
+		"trailing data":    `{} {}`,
+		"duplicate member": `{"a":1,"a":2}`,
+		"invalid utf-8":    "\"\xff\"",
+		"number too large": `1e400`,
+		"lone surrogate":   `"\ud800"`,
+		"lone low":         `"a\uDC00"`,
+		"reversed pair":    `"\udc00\ud800"`,
+		"high then text":   `"\ud83dx"`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := jcsJSON([]byte(in)); !errors.Is(err, errInvalidJSON) {
+				t.Errorf("expected an invalid JSON error, got %v", err)
+			}
+		})
+	}
+}
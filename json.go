@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	errInvalidJSON = errors.New("input is not valid JSON")
+	errJSONPointer = errors.New("JSON pointer does not resolve")
+)
+
+// canonicalJSON re-encodes the given JSON document with its object keys sorted,
+// all insignificant whitespace removed, and its numbers written in a single
+// form, so 1, 1.0 and 1e0 are the same.
+//
+// Only this canonical form is signed or verified, so any two serializations of
+// the same document yield the same signature.
+func canonicalJSON(in []byte) ([]byte, error) {
+	v, err := decodeJSON(in)
+	if err != nil {
+		return nil, err
+	}
+	return encodeCanonicalJSON(v)
+}
+
+// canonicalNumber writes the number literal as [jcsNumber] would, but with
+// all of its digits, so numbers which don't fit a float64 are kept exactly.
+func canonicalNumber(num json.Number) (json.Number, error) {
+	s := strings.TrimPrefix(string(num), "-")
+	sign := ""
+	if len(s) < len(num) {
+		sign = "-"
+	}
+	mantissa, exp, _ := strings.Cut(strings.ToLower(s), "e")
+	x := 0
+	if exp != "" {
+		var err error
+		if x, err = strconv.Atoi(exp); err != nil {
+			return "", fmt.Errorf("%w: number %s is too large", errInvalidJSON, num)
+		}
+	}
+	whole, frac, _ := strings.Cut(mantissa, ".")
+	// the value is then 0.digits×10^n.
+	digits := strings.TrimLeft(whole+frac, "0")
+	n := x + len(whole) - (len(whole+frac) - len(digits))
+	digits = strings.TrimRight(digits, "0")
+	if digits == "" {
+		return "0", nil
+	}
+	return json.Number(formatNumber(sign, digits, n)), nil
+}
+
+// canonicalNumbers replaces the numbers in v with their [canonicalNumber].
+func canonicalNumbers(v any) (any, error) {
+	var err error
+	switch v := v.(type) {
+	case json.Number:
+		return canonicalNumber(v)
+	case map[string]any:
+		for k, child := range v {
+			if v[k], err = canonicalNumbers(child); err != nil {
+				return nil, err
+			}
+		}
+	case []any:
+		for i, child := range v {
+			if v[i], err = canonicalNumbers(child); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return v, nil
+}
+
+// jsonPointerSubset returns the canonical form, as [canonicalJSON], of the
+// value the RFC 6901 pointer, e.g. "/spec/template", selects in the given
+// JSON document.
+func jsonPointerSubset(in []byte, pointer string) ([]byte, error) {
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: %q must start with /", errJSONPointer, pointer)
+	}
+	v, err := decodeJSON(in)
+	if err != nil {
+		return nil, err
+	}
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		switch node := v.(type) {
+		case map[string]any:
+			child, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s has no member %q", errJSONPointer, pointer, token)
+			}
+			v = child
+		case []any:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(node) || (token != "0" && strings.HasPrefix(token, "0")) {
+				return nil, fmt.Errorf("%w: %s has no index %q", errJSONPointer, pointer, token)
+			}
+			v = node[i]
+		default:
+			return nil, fmt.Errorf("%w: %s goes through a value that is not an object or an array", errJSONPointer, pointer)
+		}
+	}
+	return encodeCanonicalJSON(v)
+}
+
+func decodeJSON(in []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(in))
+	dec.UseNumber()
+
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidJSON, err)
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("%w: unexpected data after top-level value", errInvalidJSON)
+	}
+	return v, nil
+}
+
+func encodeCanonicalJSON(v any) ([]byte, error) {
+	v, err := canonicalNumbers(v)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, fmt.Errorf("could not canonicalize JSON: %w", err)
+	}
+	return bytes.TrimSuffix(out.Bytes(), []byte("\n")), nil
+}
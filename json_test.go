@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestCanonicalJSONNumbers(t *testing.T) {
+	for in, want := range map[string]string{
+		`[1, 1.0, 1e0, 10E-1, 0.1e1, 100e-2]`:      `[1,1,1,1,1,1]`,
+		`[0, -0, 0.0, 0e10, -0.000]`:               `[0,0,0,0,0]`,
+		`[4.50, 2e-3, 1E30, 1e21, 1e20, 0.000001]`: `[4.5,0.002,1e+30,1e+21,100000000000000000000,0.000001]`,
+		`[1e-7, -12.5e-10, 123.456e1]`:             `[1e-7,-1.25e-9,1234.56]`,
+		// beyond a float64, the digits are kept.
+		`[12345678901234567890, 12345678901234567891]`: `[12345678901234567890,12345678901234567891]`,
+		`[0.10000000000000000000000001, 1e400]`:        `[0.10000000000000000000000001,1e+400]`,
+		`{"b": {"c": [2.0]}, "a": 3e2}`:                `{"a":300,"b":{"c":[2]}}`,
+	} {
+		got, err := canonicalJSON([]byte(in))
+		if err != nil {
+			t.Fatalf("%s: %v", in, err)
+		}
+		if string(got) != want {
+			t.Errorf("%s: expected %s, got %s", in, want, got)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"time"
+)
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Error     *junitFailure `xml:"error,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// printJUnit writes the results as a JUnit XML report, with one test case
+// per file.
+//
+// Files whose signature did not verify are failures, while files that could
+// not be read, e.g. because the signature is missing, are errors, as in the
+// JUnit schema.
+func printJUnit(w io.Writer, name string, results []batchResult) error {
+	suite := junitTestSuite{
+		Name:  name,
+		Tests: len(results),
+	}
+	var total time.Duration
+	for _, r := range results {
+		total += r.Elapsed
+		tc := junitTestCase{
+			Name:      r.File,
+			Classname: "ssign.verify",
+			Time:      junitTime(r.Elapsed),
+		}
+		if r.Err != nil {
+			f := &junitFailure{
+				Message: r.Err.Error(),
+				Type:    string(codeOf(r.Err)),
+				Text:    r.Err.Error(),
+			}
+			if codeOf(r.Err) == codeIO {
+				tc.Error = f
+				suite.Errors++
+			} else {
+				tc.Failure = f
+				suite.Failures++
+			}
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	suite.Time = junitTime(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// junitTime formats a duration in seconds, as used by the time attributes.
+func junitTime(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+var errKeygenUnavailable = errors.New("ssh-keygen is not available")
+
+// keygenVerify verifies the signed message with "ssh-keygen -Y verify",
+// trusting the given keys, and returns its output.
+//
+// It's meant to compare ssign with OpenSSH when they might disagree, so the
+// signature is given to ssh-keygen without the PEM headers ssign adds, which
+// it does not support.
+func keygenVerify(pubs []ssh.PublicKey, message, signature []byte, namespace string) (string, error) {
+	keygen, err := exec.LookPath("ssh-keygen")
+	if err != nil {
+		return "", errKeygenUnavailable
+	}
+
+	raw, err := decodeSignature(signature)
+	if err != nil {
+		return "", err
+	}
+	armored, err := encodeSignature(raw, "pem", nil)
+	if err != nil {
+		return "", err
+	}
+
+	dir, err := os.MkdirTemp("", "ssign-keygen-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	var signers bytes.Buffer
+	for _, pub := range pubs {
+		fmt.Fprintf(&signers, "ssign namespaces=%q %s", namespace, ssh.MarshalAuthorizedKey(pub))
+	}
+	allowed := filepath.Join(dir, "allowed_signers")
+	if err := os.WriteFile(allowed, signers.Bytes(), 0o600); err != nil {
+		return "", err
+	}
+	sigName := filepath.Join(dir, "message.sig")
+	if err := os.WriteFile(sigName, armored, 0o600); err != nil {
+		return "", err
+	}
+
+	var out bytes.Buffer
+	cmd := exec.Command(keygen, "-Y", "verify", "-f", allowed, "-I", "ssign", "-n", namespace, "-s", sigName)
+	cmd.Stdin = bytes.NewReader(message)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err = cmd.Run()
+	return strings.TrimSpace(out.String()), err
+}
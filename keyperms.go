@@ -0,0 +1,23 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"runtime"
+)
+
+var errKeyPermissions = errors.New("key permissions are too open")
+
+// checkKeyPermissions refuses private keys readable or writable by the group
+// or others, as OpenSSH does. Windows has no POSIX modes, so it's not
+// checked there.
+func checkKeyPermissions(name string, info fs.FileInfo) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	if mode := info.Mode().Perm(); mode&0o077 != 0 {
+		return fmt.Errorf("%w: mode %04o, it must only be accessible by its owner, run \"chmod 600 %s\" or use --ignore-permissions", errKeyPermissions, mode, name)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+var errHostNotFound = errors.New("host not found")
+
+// openKnownHostKeys returns the keys recorded for host in the given
+// known_hosts file. Keys marked as @revoked, for any host as in OpenSSH, are
+// left out, and certificate authorities are ignored.
+//
+// A line that can't be parsed is an error, rather than skipped, as it could
+// be a revocation.
+func openKnownHostKeys(name, host string) ([]ssh.PublicKey, error) {
+	in, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	host = knownhosts.Normalize(host)
+	var pubs []ssh.PublicKey
+	revoked := map[string]bool{}
+	for i, line := range bytes.Split(in, []byte("\n")) {
+		marker, hosts, pub, _, _, err := ssh.ParseKnownHosts(line)
+		switch {
+		case err == io.EOF:
+			// a blank or comment line.
+			continue
+		case err != nil:
+			return nil, fmt.Errorf("%s:%d: %w", name, i+1, err)
+		case marker == "revoked":
+			revoked[string(pub.Marshal())] = true
+		case marker == "" && matchKnownHost(hosts, host):
+			pubs = append(pubs, pub)
+		}
+	}
+	pubs = slices.DeleteFunc(pubs, func(pub ssh.PublicKey) bool {
+		return revoked[string(pub.Marshal())]
+	})
+	if len(pubs) == 0 {
+		return nil, errHostNotFound
+	}
+	return pubs, nil
+}
+
+// matchKnownHost reports whether host matches the patterns of a known_hosts
+// line, which can be hashed, use wildcards, or be negated.
+func matchKnownHost(patterns []string, host string) bool {
+	var matched bool
+	for _, p := range patterns {
+		negated := strings.HasPrefix(p, "!")
+		p = strings.TrimPrefix(p, "!")
+		if !matchKnownHostPattern(p, host) {
+			continue
+		}
+		if negated {
+			return false
+		}
+		matched = true
+	}
+	return matched
+}
+
+func matchKnownHostPattern(pattern, host string) bool {
+	if salt, hash, ok := strings.Cut(strings.TrimPrefix(pattern, "|1|"), "|"); ok && strings.HasPrefix(pattern, "|1|") {
+		key, err := base64.StdEncoding.DecodeString(salt)
+		if err != nil {
+			return false
+		}
+		want, err := base64.StdEncoding.DecodeString(hash)
+		if err != nil {
+			return false
+		}
+		mac := hmac.New(sha1.New, key)
+		mac.Write([]byte(host))
+		return bytes.Equal(mac.Sum(nil), want)
+	}
+	if !strings.ContainsAny(pattern, "*?") {
+		return pattern == host
+	}
+	// only * and ? are wildcards, brackets are used for ports.
+	pattern = strings.NewReplacer("[", `\[`, "]", `\]`).Replace(pattern)
+	ok, _ := path.Match(pattern, host)
+	return ok
+}
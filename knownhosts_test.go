@@ -0,0 +1,87 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestOpenKnownHostKeys(t *testing.T) {
+	keyA, keyB := testPublicKey(t), testPublicKey(t)
+	a := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(keyA)))
+	b := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(keyB)))
+
+	for name, tt := range map[string]struct {
+		lines []string
+		want  []ssh.PublicKey
+		err   error
+	}{
+		"keys": {
+			lines: []string{"# comment", "", "example.com " + a, "other.com " + b, "*.com " + b},
+			want:  []ssh.PublicKey{keyA, keyB},
+		},
+		"revoked": {
+			lines: []string{"example.com " + a, "example.com " + b, "@revoked * " + b},
+			want:  []ssh.PublicKey{keyA},
+		},
+		"revoked for another host": {
+			lines: []string{"example.com " + a, "@revoked other.com " + a},
+			err:   errHostNotFound,
+		},
+		"not found": {
+			lines: []string{"other.com " + a},
+			err:   errHostNotFound,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := openKnownHostKeys(writeKnownHosts(t, tt.lines), "example.com")
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %d keys, got %d", len(tt.want), len(got))
+			}
+			for i := range got {
+				if string(got[i].Marshal()) != string(tt.want[i].Marshal()) {
+					t.Errorf("key %d: expected %s, got %s", i, ssh.FingerprintSHA256(tt.want[i]), ssh.FingerprintSHA256(got[i]))
+				}
+			}
+		})
+	}
+}
+
+func TestOpenKnownHostKeysInvalidLine(t *testing.T) {
+	a := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(testPublicKey(t))))
+	name := writeKnownHosts(t, []string{"example.com " + a, "@revoked * ssh-ed25519 AAAAnope"})
+	if _, err := openKnownHostKeys(name, "example.com"); err == nil || !strings.Contains(err.Error(), ":2:") {
+		t.Errorf("expected an error on line 2, got %v", err)
+	}
+}
+
+func writeKnownHosts(t *testing.T, lines []string) string {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "known_hosts")
+	if err := os.WriteFile(name, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func testPublicKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
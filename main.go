@@ -3,151 +3,2451 @@ package main
 import (
 	"context"
 	"crypto/rand"
+	"encoding/json"
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"charm.land/huh/v2"
 	"charm.land/lipgloss/v2"
 	"github.com/caarlos0/sshsig"
+	"github.com/charmbracelet/colorprofile"
 	"github.com/charmbracelet/fang"
 	"github.com/charmbracelet/x/exp/charmtone"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"gopkg.in/yaml.v3"
 )
 
-const namespace = "ssign@becker.software"
+const defaultNamespace = "ssign@becker.software"
 
 func main() {
+	var listErrors bool
+	var dumpCurves string
+	var showRawError bool
 	cmd := &cobra.Command{
 		Use:   "ssign",
 		Short: "sign and verify files using SSH signatures",
+		Long: `Sign and verify files using SSH signatures.
+
+Set --no-fang (or SSIGN_NO_FANG=1) to run without fang: output is plain text
+without colors, errors are printed as-is by cobra followed by the usage, and
+there's no styled help or version handling.`,
 		Example: `ssign sign --key ./id_ed25519 file file.sig
-ssign verify --public-key ./id_ed25519.pub file file.sig`,
+ssign verify --public-key ./id_ed25519.pub file file.sig
+ssign --dump-supported-curves=json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			switch dumpCurves {
+			case "":
+			case "json":
+				return printJSON(cmd.OutOrStdout(), supportedCurves())
+			case "text":
+				for _, s := range supportedCurves() {
+					curve := s.Curve
+					if curve == "" {
+						curve = "-"
+					}
+					cmd.Println(strings.TrimSpace(fmt.Sprintf("%-20s %-6s sign: %-4s verify: %-4s %s", s.KeyType, curve, yesNo(s.Sign), yesNo(s.Verify), s.Error)))
+				}
+				return nil
+			default:
+				return fmt.Errorf("invalid --dump-supported-curves format %q, expected text or json", dumpCurves)
+			}
+			if !listErrors {
+				return cmd.Help()
+			}
+			for _, e := range errorCatalog {
+				cmd.Printf("%-20s %s\n", e.Code, e.Description)
+			}
+			return nil
+		},
 	}
+	cmd.Flags().BoolVar(&listErrors, "list-errors", false, "List the error codes used in JSON output")
+	cmd.Flags().StringVar(&dumpCurves, "dump-supported-curves", "", "Try signing and verifying with a throwaway key of each type and ECDSA curve, and list which ones this build supports, as text or json")
+	cmd.Flags().Lookup("dump-supported-curves").NoOptDefVal = "text"
 
 	var keyPath string
+	var namespace string
+	var jsonCanonical, jcs bool
+	var jsonPointer string
+	var stripBOMs bool
+	var crlf bool
+	var unicodeForm string
+	readBufferSize := byteSize(defaultReadBufferSize)
+	var useXattr bool
+	var outputTemplate string
+	var jsonOutput bool
+	var redactJSON bool
+	var keySel keySelector
+	var passphraseCommand string
+	var verbose bool
+	var useAgent, retryLockedKey bool
+	var attest []string
+	var gitRange, gitFormat string
+	var gitObject string
+	var receiptPath string
+	var abortOnWeakRandomness bool
+	var stdinName string
+	var force, stdoutSignatureOnly bool
+	var ephemeralKey bool
+	var teePath, sigPath string
+	var signCommand string
+	var emitFingerprint bool
+	var continueFrom string
+	var ignorePermissions bool
+	var pemWrap int
+	var combine bool
+	var reproTar bool
+	var both, inline bool
+	var outURL string
+	var keyID string
+	var allowSpecialFile, requireRealFile bool
+	var twoPhase, yes bool
+	runResume := func(cmd *cobra.Command, dir string, key ssh.Signer, keyName string) error {
+		files, err := filesToSign(dir, continueFrom)
+		if err != nil {
+			return fmt.Errorf("could not list files in %s: %w", dir, err)
+		}
+		state, err := openSignState(continueFrom, ssh.FingerprintSHA256(key.PublicKey()), namespace)
+		if err != nil {
+			return fmt.Errorf("could not open state %s: %w", continueFrom, err)
+		}
+		defer state.Close()
+
+		counts, err := signDir(files, state, key, namespace, pemWrap, readBufferSize)
+		if err != nil {
+			return fmt.Errorf("could not sign %s, run it again to resume after the %d files signed: %w", dir, counts.Signed+counts.Resumed, err)
+		}
+
+		styles := mustStyles()
+		cmd.Println(styles.Header.String())
+		cmd.Println(styles.Text.Render(
+			"Signed " +
+				styles.Code.Render(fmt.Sprintf("%d", counts.Signed)) +
+				" files in " +
+				styles.Code.Render(dir) +
+				" with " +
+				styles.Code.Render(keyName) +
+				".",
+		))
+		cmd.Println(styles.Text.Render(
+			"Skipped " +
+				styles.Code.Render(fmt.Sprintf("%d", counts.Resumed)) +
+				" files already signed, progress is recorded in " +
+				styles.Code.Render(continueFrom) +
+				".",
+		))
+		return nil
+	}
 	signCmd := &cobra.Command{
 		Use:   "sign",
 		Short: "Sign a file",
-		Args:  cobra.RangeArgs(1, 2),
+		Args: func(cmd *cobra.Command, args []string) error {
+			if cmd.Flags().Changed("combine") {
+				return cobra.MinimumNArgs(2)(cmd, args)
+			}
+			return cobra.RangeArgs(1, 2)(cmd, args)
+		},
 		Example: `ssign sign README.md
-ssign sign --key id_ed25519 README.md README.sig`,
+ssign sign --key id_ed25519 README.md README.sig
+ssign sign --git-range v1.0..v1.1 v1.1.patch.ssig
+ssign sign --git-object 3b18e512dba79e4c8300dd08aeb37f8e728b8dad README.md.ssig
+ssign sign --receipt README.md.receipt.json README.md
+ssign sign --out s3://releases/app.tar.gz.ssig app.tar.gz
+curl -sL https://example.com/artifact.bin | ssign sign --stdin-name artifact.bin -
+curl -sL https://example.com/big.iso | ssign sign --tee big.iso --sig big.iso.ssig -
+ssign sign --command 'mybuild --emit' --tee artifact artifact.ssig
+ssign sign --continue-from sign.state dist/
+ssign sign --combine a.bin b.bin c.bin combined.ssig
+ssign sign --reproducible-tar dist/
+ssign sign --both README.md
+SIG="$(ssign sign --stdout-signature-only README.md)"`,
 		Aliases: []string{"s"},
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			subject := args[0]
+			switch {
+			case gitRange != "":
+				subject = "git " + gitFormat + " " + gitRange
+			case gitObject != "":
+				subject = "git object " + gitObject
+			case signCommand != "" && teePath == "":
+				subject = "output of " + signCommand
+			case stdinName != "":
+				subject = stdinName
+			case teePath != "":
+				subject = teePath
+			case combine:
+				subject = strings.Join(args[:len(args)-1], ", ")
+			case reproTar:
+				subject = filepath.Clean(args[0])
+			}
+			if jsonOutput {
+				defer func() {
+					if err != nil {
+						printJSONError(cmd.OutOrStdout(), result{File: subject, Key: keyPath, Namespace: namespace}, err, redactJSON)
+					}
+				}()
+			}
+
+			if pemWrap < 1 {
+				return fmt.Errorf("invalid --pem-wrap %d, expected a positive width", pemWrap)
+			}
+			if err := checkUnicodeForm(unicodeForm); err != nil {
+				return err
+			}
+			// the conflicts between flags are in flagConflicts.
+			if stdoutSignatureOnly && len(args) > 1 {
+				return fmt.Errorf("--stdout-signature-only cannot be used with a signature path")
+			}
+			if outURL != "" && len(args) > 1 {
+				return fmt.Errorf("--out cannot be used with a signature path")
+			}
+			if outURL != "" {
+				if _, _, _, err := parseObjectURL(outURL); err != nil {
+					return err
+				}
+			}
+			if keyID != "" && (strings.ContainsAny(keyID, `/\`) || keyID == "." || keyID == "..") {
+				return fmt.Errorf("invalid --key-id %q, it's part of the signature file name", keyID)
+			}
+			if yes && !twoPhase {
+				return fmt.Errorf("--yes requires --two-phase")
+			}
+			if sigPath != "" && teePath == "" {
+				return fmt.Errorf("--sig requires --tee")
+			}
+			if emitFingerprint && signCommand != "" && teePath == "" {
+				return fmt.Errorf("--emit-fingerprint-file requires signing a file, not --command without --tee")
+			}
+			if both && !force && exists(subject+inlineSuffix) {
+				return fmt.Errorf("could not write inline signature %s: it already exists, use --force to overwrite it", subject+inlineSuffix)
+			}
+			if emitFingerprint && !force && exists(subject+fingerprintFileSuffix) {
+				return fmt.Errorf("could not write fingerprint %s: it already exists, use --force to overwrite it", subject+fingerprintFileSuffix)
+			}
+
+			var message, digest []byte
+			switch {
+			case combine:
+				message, err = combinedMessage(args[:len(args)-1], readBufferSize)
+				if err != nil {
+					return fmt.Errorf("could not sign: %w", err)
+				}
+			case reproTar:
+				message, err = reproducibleTar(subject)
+				if err != nil {
+					return fmt.Errorf("could not sign %s: %w", subject, err)
+				}
+			case continueFrom != "":
+				if len(args) > 1 {
+					return fmt.Errorf("--continue-from only takes a directory")
+				}
+				if info, err := os.Stat(args[0]); err != nil || !info.IsDir() {
+					return fmt.Errorf("--continue-from requires a directory")
+				}
+			case signCommand != "":
+				if len(args) > 1 {
+					return fmt.Errorf("--command only takes the signature path")
+				}
+				digest, err = commandDigest(signCommand, cmd.InOrStdin(), cmd.ErrOrStderr(), teePath, force, readBufferSize)
+				if err != nil {
+					return fmt.Errorf("could not sign %s: %w", subject, err)
+				}
+			case teePath != "":
+				if args[0] != "-" || len(args) > 1 {
+					return fmt.Errorf("--tee reads from -, and cannot be used with a signature path")
+				}
+				digest, err = teeDigest(cmd.InOrStdin(), teePath, force, readBufferSize)
+				if err != nil {
+					return fmt.Errorf("could not write %s: %w", teePath, err)
+				}
+			case gitRange != "":
+				if len(args) > 1 {
+					return fmt.Errorf("--git-range only takes the signature path")
+				}
+				message, err = gitRangeContent(gitRange, gitFormat)
+				if err != nil {
+					return fmt.Errorf("could not generate %s: %w", subject, err)
+				}
+			case gitObject != "":
+				if len(args) > 1 {
+					return fmt.Errorf("--git-object only takes the signature path")
+				}
+				var oid string
+				oid, message, err = gitObjectContent("", gitObject)
+				if err != nil {
+					return fmt.Errorf("could not read %s: %w", subject, err)
+				}
+				subject = "git object " + oid
+			case stdinName != "" || args[0] == "-":
+				if args[0] != "-" || stdinName == "" {
+					return fmt.Errorf("--stdin-name and - must be used together")
+				}
+				// stdin is only kept in memory when it's needed whole,
+				// otherwise it's hashed as it's written, like with --tee.
+				if stripBOMs || crlf || unicodeForm != "" || jsonCanonical || jcs || jsonPointer != "" || len(attest) > 0 || twoPhase || both || receiptPath != "" {
+					message, err = teeFile(cmd.InOrStdin(), stdinName, force, readBufferSize)
+				} else {
+					digest, err = teeDigest(cmd.InOrStdin(), stdinName, force, readBufferSize)
+				}
+				if err != nil {
+					return fmt.Errorf("could not write %s: %w", stdinName, err)
+				}
+			default:
+				if typ, err := specialFileType(args[0]); err == nil && typ != "" && !allowSpecialFile {
+					// devices like /dev/zero never end, or give meaningless
+					// content, unlike pipes, e.g. <(command).
+					if requireRealFile || strings.HasSuffix(typ, "device") {
+						return fmt.Errorf("could not sign %s: it's a %s, not a regular file, use --allow-special-file to sign what is read from it", args[0], typ)
+					}
+					cmd.PrintErrf("Warning: %s is a %s, not a regular file, signing what is read from it.\n", args[0], typ)
+				}
+				message, err = readFile(args[0], readBufferSize)
+				if err != nil {
+					return fmt.Errorf("could open file %s: %w", args[0], err)
+				}
+			}
+
+			content := message
+			if stripBOMs {
+				message = stripBOM(message)
+			}
+			if crlf {
+				message = crlfNewlines(message)
+			}
+			message = normalizeUnicode(message, unicodeForm)
+			switch {
+			case jsonPointer != "":
+				message, err = jsonPointerSubset(message, jsonPointer)
+				if err != nil {
+					return fmt.Errorf("could not sign %s: %w", subject, err)
+				}
+			case jsonCanonical:
+				message, err = canonicalJSON(message)
+				if err != nil {
+					return fmt.Errorf("could not sign %s: %w", subject, err)
+				}
+			case jcs:
+				message, err = jcsJSON(message)
+				if err != nil {
+					return fmt.Errorf("could not sign %s: %w", subject, err)
+				}
+			}
+
+			fields, err := parseAttestations(attest)
+			if err != nil {
+				return err
+			}
+			if len(fields) > 0 {
+				message = attestedMessage(message, fields)
+			}
+
+			var key ssh.Signer
+			keyName := keyPath
+			switch {
+			case ephemeralKey:
+				if abortOnWeakRandomness {
+					// the key itself is generated from it.
+					if err := checkRandomness(); err != nil {
+						return fmt.Errorf("could not generate key: %w", err)
+					}
+				}
+				key, err = newEphemeralKey()
+				if err != nil {
+					return fmt.Errorf("could not generate key: %w", err)
+				}
+				keyName = "ephemeral key " + ssh.FingerprintSHA256(key.PublicKey())
+			case useAgent:
+				var closeAgent func() error
+				key, closeAgent, err = openAgentKey(keySel, newPrompter(cmd))
+				if err != nil {
+					return withCode(codeKey, err)
+				}
+				defer closeAgent()
+				keyName = "agent key " + ssh.FingerprintSHA256(key.PublicKey())
+			default:
+				p := newPrompter(cmd)
+				p.PassphraseCommand = passphraseCommand
+				key, err = openPrivateKey(keyPath, keySel, p, ignorePermissions)
+				if err != nil {
+					return fmt.Errorf("key %s: %w", keyPath, withCode(codeKey, err))
+				}
+			}
+			if verbose {
+				cmd.PrintErrf("Using key %s from %s\n", ssh.FingerprintSHA256(key.PublicKey()), keyName)
+			}
+			if abortOnWeakRandomness && needsRandomness(key.PublicKey()) {
+				if err := checkRandomness(); err != nil {
+					return fmt.Errorf("could not sign: %w", err)
+				}
+			}
+
+			if continueFrom != "" {
+				return runResume(cmd, args[0], key, keyName)
+			}
+
+			sign := func() ([]byte, error) {
+				if digest != nil {
+					return signDigest(key, digest, namespace)
+				}
+				return signMessage(key, message, namespace)
+			}
+			data, err := sign()
+			for attempt := 1; retryLockedKey && errors.Is(err, errKeyLocked) && attempt < lockedKeyAttempts; attempt++ {
+				cmd.PrintErrln("The agent refused to sign, retrying...")
+				data, err = sign()
+			}
+			if err != nil {
+				return err
+			}
+			if len(fields) > 0 {
+				data, err = withAttestationHeaders(data, fields)
+				if err != nil {
+					return fmt.Errorf("could not sign: %w", err)
+				}
+			}
+			if pemWrap != defaultPEMWrap {
+				data, err = wrapPEM(data, pemWrap)
+				if err != nil {
+					return fmt.Errorf("could not sign: %w", err)
+				}
+			}
+			if twoPhase {
+				p := newPrompter(cmd)
+				printSignatureReview(p.Out, subject, content, key.PublicKey(), namespace, data)
+				if !yes {
+					if err := confirmSignature(p, subject); err != nil {
+						return err
+					}
+				}
+			}
+
+			var sigName string
+			switch {
+			case stdoutSignatureOnly:
+				sigName = "stdout"
+				if _, err := cmd.OutOrStdout().Write(data); err != nil {
+					return fmt.Errorf("could not write signature: %w", err)
+				}
+			case useXattr:
+				if len(args) > 1 {
+					return fmt.Errorf("cannot use a signature path with --xattr")
+				}
+				sigName = "xattr " + signatureXattr
+				if err := setSignatureXattr(subject, data); err != nil {
+					return fmt.Errorf("could not write signature to %s: %w", subject, err)
+				}
+			case outURL != "":
+				sigName = outURL
+				if err := writeObject(cmd.Context(), outURL, data, force); err != nil {
+					return fmt.Errorf("could not upload signature to %s: %w", outURL, err)
+				}
+			default:
+				switch {
+				case sigPath != "":
+					sigName = sigPath
+				case gitRange != "" || gitObject != "" || signCommand != "":
+					sigName = args[0]
+				case combine:
+					sigName = args[len(args)-1]
+				case len(args) > 1:
+					sigName = args[1]
+				case keyID != "":
+					sigName = subject + "." + keyID + ".ssig"
+				default:
+					sigName = subject + ".ssig"
+				}
+				if err := os.WriteFile(sigName, data, 0o644); err != nil {
+					return fmt.Errorf("could not write signature %s: %w", sigName, err)
+				}
+			}
+			if ephemeralKey {
+				if err := os.WriteFile(sigName+".pub", ssh.MarshalAuthorizedKey(key.PublicKey()), 0o644); err != nil {
+					return fmt.Errorf("could not write public key %s: %w", sigName+".pub", err)
+				}
+			}
+			if both {
+				if err := writeNewFile(subject+inlineSuffix, inlineSigned(content, data), force); err != nil {
+					return fmt.Errorf("could not write inline signature %s: %w", subject+inlineSuffix, err)
+				}
+			}
+			if emitFingerprint {
+				if err := writeNewFile(subject+fingerprintFileSuffix, []byte(ssh.FingerprintSHA256(key.PublicKey())+"\n"), force); err != nil {
+					return fmt.Errorf("could not write fingerprint %s: %w", subject+fingerprintFileSuffix, err)
+				}
+			}
+
+			res := result{
+				File:        subject,
+				Signature:   sigName,
+				Key:         keyName,
+				KeyID:       keyID,
+				Fingerprint: ssh.FingerprintSHA256(key.PublicKey()),
+				Namespace:   namespace,
+			}
+			if receiptPath != "" {
+				if err := writeReceipt(receiptPath, newReceipt(res, content)); err != nil {
+					return fmt.Errorf("could not write receipt %s: %w", receiptPath, err)
+				}
+			}
+			if jsonOutput {
+				jr := jsonResult{result: res, OK: true}
+				if redactJSON {
+					jr = jr.redacted()
+				}
+				return printJSON(cmd.OutOrStdout(), jr)
+			}
+			if outputTemplate != "" {
+				return printTemplate(cmd.OutOrStdout(), outputTemplate, res)
+			}
+
+			printLine := cmd.Println
+			if stdoutSignatureOnly {
+				printLine = cmd.PrintErrln
+			}
+			styles := mustStyles()
+			signedWith := styles.Code.Render(keyName)
+			if keyID != "" {
+				signedWith = styles.Code.Render(keyID) + " from " + signedWith
+			}
+			printLine(styles.Header.String())
+			printLine(styles.Text.Render(
+				"Signed " +
+					styles.Code.Render(subject) +
+					" with " +
+					signedWith +
+					".",
+			))
+			printLine(styles.Text.Render(
+				"Signature stored at " +
+					styles.Code.Render(sigName) +
+					".",
+			))
+			if both {
+				printLine(styles.Text.Render(
+					"Inline signed copy stored at " +
+						styles.Code.Render(subject+inlineSuffix) +
+						".",
+				))
+			}
+			if emitFingerprint {
+				printLine(styles.Text.Render(
+					"Key fingerprint stored at " +
+						styles.Code.Render(subject+fingerprintFileSuffix) +
+						".",
+				))
+			}
+			if jsonPointer != "" {
+				printLine(styles.Text.Render(
+					"Only " +
+						styles.Code.Render(jsonPointer) +
+						" of the document is covered by the signature.",
+				))
+			}
+			if signCommand != "" {
+				printLine(styles.Text.Render(
+					"Command exited with status " +
+						styles.Code.Render("0") +
+						".",
+				))
+			}
+			if ephemeralKey {
+				printLine(styles.Text.Render(
+					"Public key stored at " +
+						styles.Code.Render(sigName+".pub") +
+						", share it through a channel verifiers trust.",
+				))
+			}
+			return nil
+		},
+	}
+	signCmd.PersistentFlags().StringVar(&keyPath, "key", os.ExpandEnv("$HOME/.ssh/id_ed25519"), "SSH Key to be used")
+	signCmd.PersistentFlags().StringVar(&namespace, "namespace", defaultNamespace, "Namespace of the signature")
+	signCmd.PersistentFlags().BoolVar(&ephemeralKey, "ephemeral-key", false, "Sign with a new Ed25519 key that is never written, and store its public key next to the signature with a .pub extension (anyone can make such signatures, they are only as trustworthy as the way the public key is shared)")
+	signCmd.PersistentFlags().BoolVar(&useAgent, "agent", false, "Sign with a key from the SSH agent instead of a key file")
+	signCmd.PersistentFlags().BoolVar(&retryLockedKey, "retry-on-locked-key", false, fmt.Sprintf("Try up to %d times when the agent refuses to sign, e.g. a declined confirmation", lockedKeyAttempts))
+	signCmd.PersistentFlags().BoolVar(&ignorePermissions, "ignore-permissions", false, "Use the key even if its file is accessible by the group or others, which is otherwise refused as OpenSSH does")
+	signCmd.PersistentFlags().StringVar(&passphraseCommand, "passphrase-command", "", "Run this command, e.g. \"pass show ssh/key\", and use its output as the key passphrase instead of asking for it")
+	signCmd.PersistentFlags().IntVar(&keySel.Index, "key-index", -1, "Index of the key to use when the key file or agent holds more than one")
+	signCmd.PersistentFlags().StringVar(&keySel.Fingerprint, "key-fingerprint", "", "SHA256 fingerprint of the key to use when the key file or agent holds more than one")
+	signCmd.PersistentFlags().BoolVar(&abortOnWeakRandomness, "abort-on-weak-randomness", true, "Refuse to sign with an ECDSA or RSA key, or to generate --ephemeral-key, if the random number generator is not the system one (only ever the case when it was replaced, e.g. by a test)")
+	signCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "Print which key was used")
+	signCmd.PersistentFlags().BoolVar(&stripBOMs, "strip-bom", false, "Sign the file without its leading UTF-8 byte order mark, if any (the signed bytes are then not exactly the file's)")
+	signCmd.PersistentFlags().BoolVar(&crlf, "canonicalize-newlines-to-crlf", false, "Sign the file with its LF line endings converted to CRLF, as expected by some Windows tooling (the signed bytes are then not exactly the file's, and it must be verified with the same flag)")
+	signCmd.PersistentFlags().StringVar(&unicodeForm, "normalize-unicode", "", "Sign the text with its Unicode normalized to this form, nfc or nfd, so it verifies whichever form it is written in (the signed bytes are then not exactly the file's, and it must be verified with the same flag)")
+	signCmd.PersistentFlags().BoolVar(&jsonCanonical, "json-canonical", false, "Sign the canonical form of a JSON file (sorted keys, no whitespace, numbers in a single form) instead of its raw bytes")
+	signCmd.PersistentFlags().BoolVar(&jcs, "jcs", false, "Sign the RFC 8785 canonical form (JSON Canonicalization Scheme) of a JSON file instead of its raw bytes, so signatures interoperate with other JCS implementations (the file must be I-JSON: no duplicate members, and numbers that fit a float64)")
+	signCmd.PersistentFlags().StringVar(&jsonPointer, "json-pointer", "", "Only sign the value this JSON pointer, e.g. /spec/template, selects in the JSON file, in canonical form as with --json-canonical: the rest of the file is not covered by the signature")
+	signCmd.PersistentFlags().StringArrayVar(&attest, "attest", nil, "Attestation field (key=value) signed together with the file, can be repeated")
+	signCmd.PersistentFlags().BoolVar(&emitFingerprint, "emit-fingerprint-file", false, "Also write the SHA256 fingerprint of the signing key to the file name plus .fpr, so verifiers know which key to get before downloading it (it is not a trusted key)")
+	signCmd.PersistentFlags().BoolVar(&reproTar, "reproducible-tar", false, "Sign a directory as a normalized tar stream of its content (sorted entries, zeroed times and owners, normalized modes), the same regardless of the file system order and metadata, storing the signature at the directory name plus .ssig by default (verify it with \"ssign verify --reproducible-tar\")")
+	signCmd.PersistentFlags().BoolVar(&combine, "combine", false, "Sign the given files together, in order, with a single signature, the last argument being the signature path (verify it with \"ssign verify --combine\" and the files in the same order)")
+	signCmd.PersistentFlags().IntVar(&pemWrap, "pem-wrap", defaultPEMWrap, "Width of the base64 lines of the PEM signature, for tools expecting another wrapping, e.g. 76 (any wrapping is accepted by verify)")
+	signCmd.PersistentFlags().BoolVar(&stdoutSignatureOnly, "stdout-signature-only", false, "Print the signature to stdout instead of writing it to a file, the styled output goes to stderr")
+	signCmd.PersistentFlags().StringVar(&stdinName, "stdin-name", "", "Read the file to sign from stdin (with - as the file argument), and save it with this name next to its signature, hashing it as it's read (it's read in memory with --strip-bom, --canonicalize-newlines-to-crlf, --normalize-unicode, --json-canonical, --jcs, --json-pointer, --attest, --two-phase, --both, or --receipt, which need it whole)")
+	signCmd.PersistentFlags().StringVar(&teePath, "tee", "", "Copy stdin (with - as the file argument), or the --command output, to this file, hashing it on the fly instead of keeping it in memory, and sign it")
+	signCmd.PersistentFlags().StringVar(&signCommand, "command", "", "Run this command through the shell and sign its output, hashed as it's read, failing if it exits with a non-zero status, the only argument is then the signature path (use --tee to also save the output)")
+	signCmd.PersistentFlags().StringVar(&continueFrom, "continue-from", "", "Sign each file under the directory given as argument, recording the signed ones in this state file, and skipping the files it lists as signed with the same key and namespace, or whose signature is valid and newer than them, so an interrupted run can be resumed")
+	signCmd.PersistentFlags().BoolVar(&twoPhase, "two-phase", false, "Print the SHA256 of the file, the key, the namespace, and the signature, and ask to confirm before writing it, so they can be reviewed (without a terminal, --yes is required)")
+	signCmd.PersistentFlags().BoolVar(&yes, "yes", false, "Write the --two-phase signature without asking to confirm it, still printing what it covers")
+	signCmd.PersistentFlags().BoolVar(&allowSpecialFile, "allow-special-file", false, "Sign what is read from a device, e.g. /dev/stdin, which is otherwise refused, as /dev/zero or /dev/urandom never end or give meaningless content")
+	signCmd.PersistentFlags().BoolVar(&requireRealFile, "require-real-file", false, "Also refuse named pipes, e.g. <(command), and other special files, which are otherwise signed with a warning")
+	signCmd.PersistentFlags().StringVar(&keyID, "key-id", "", "Label of the key, reported in the output, --json, and --template, and naming the signature file plus .<label>.ssig by default, so signatures by several keys sit side by side")
+	signCmd.PersistentFlags().StringVar(&outURL, "out", "", "Upload the signature to this s3://bucket/key or gs://bucket/object object instead of writing a file, using the AWS credential chain or the Google application default credentials (requires building with -tags s3 or gcs)")
+	signCmd.PersistentFlags().StringVar(&sigPath, "sig", "", "Where to store the signature of --tee (defaults to the --tee file plus .ssig)")
+	signCmd.PersistentFlags().BoolVar(&both, "both", false, "Also write the file followed by the same signature to the file name plus "+inlineSuffix+", a single inline signed file verified with \"ssign verify --inline\"")
+	signCmd.PersistentFlags().BoolVar(&force, "force", false, "Overwrite the --stdin-name, --tee, --both, or --emit-fingerprint-file file, or the --out object, if it already exists")
+	signCmd.PersistentFlags().StringVar(&gitRange, "git-range", "", "Sign the changes in this range of the current git repository (e.g. v1.0..v1.1) instead of a file, the only argument is then the signature path")
+	signCmd.PersistentFlags().StringVar(&gitObject, "git-object", "", "Sign this blob of the current git repository, by object id (or anything git resolves to a blob, e.g. v1.0:README.md) instead of a file, the only argument is then the signature path")
+	signCmd.PersistentFlags().StringVar(&gitFormat, "format", "patch", "What to sign of --git-range: patch (with the commit messages) or diff")
+	signCmd.PersistentFlags().BoolVar(&useXattr, "xattr", false, "Store the signature in the "+signatureXattr+" extended attribute of the file instead of a sidecar file")
+	signCmd.PersistentFlags().Var(&readBufferSize, "read-buffer-size", "Size of the chunks used to read the file, between 4KiB and 64MiB")
+	signCmd.PersistentFlags().StringVar(&receiptPath, "receipt", "", "Write a receipt with the file, signature, key fingerprint, namespace, and SHA256 of the file, which \"ssign verify --receipt\" checks again")
+	signCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "Print the result, or the error and its code, as JSON")
+	signCmd.PersistentFlags().BoolVar(&redactJSON, "redact-key-paths-in-json", false, "With --json, replace the file and signature paths by their SHA256, and the key path by the key fingerprint, including where they appear in the warning and error, to publish results without internal paths")
+	signCmd.PersistentFlags().StringVar(&outputTemplate, "template", "", "Go template used to print the result instead of the default output (fields: "+templateFields+")")
+	markFlagConflicts(signCmd)
+
+	var pubkeyPath string
+	var archivePath, member string
+	var dnsIdentity string
+	var pol policy
+	var output, reportFile, metricsFile string
+	var groupKeys bool
+	var manifest, manifestSignatureOnly bool
+	var sortOrder string
+	var exactManifest bool
+	var pkcs11Module, pkcs11Label string
+	var vaultKeyPath string
+	var vaultCacheTTL time.Duration
+	var explain, pauseOnError bool
+	var byContentHash bool
+	var sigDir string
+	var confirmFingerprint, trustEmbeddedKey bool
+	var messageString string
+	var knownHostsFile, knownHost string
+	var s3URL, gcsURL string
+	var filterCommand string
+	var compatKeygen bool
+	var bundlePath string
+	var namespaceMapPath string
+	var maxSignatureAge maxAge
+	var lenientUnsigned bool
+	var readAheadFiles int
+	var honorKeyExpiry bool
+	var basePath, patchPath string
+	var sbomPath, sbomComponent string
+	var useSidecar bool
+	var sigDB, sigDBKey string
+	var quietOnSuccess, silent bool
+	var pinFromFilename bool
+	var useFingerprintFile bool
+	var fingerprintFile string
+	var dryVerify bool
+	var keyIDs []string
+	var caBundle, caPrincipal string
+	var gitRepo, gitRef, gitPath string
+	runBatchVerify := func(cmd *cobra.Command, v verifier, dir string) error {
+		files, err := findSigned(dir, useXattr)
+		if err != nil {
+			return fmt.Errorf("could not list files in %s: %w", dir, err)
+		}
+		if dryVerify {
+			plans := v.planBatch(files, useXattr)
+			if jsonOutput {
+				if redactJSON {
+					for i, p := range plans {
+						plans[i] = jsonResult{result: p}.redacted().result
+					}
+				}
+				return printJSON(cmd.OutOrStdout(), plans)
+			}
+			printPlans(cmd, plans)
+			styles := mustStyles()
+			cmd.Println(styles.Text.Render(
+				"Would verify " +
+					styles.Code.Render(fmt.Sprintf("%d", len(plans))) +
+					" files in " +
+					styles.Code.Render(dir) +
+					".",
+			))
+			return nil
+		}
+		start := time.Now()
+		results := v.verifyBatch(files, useXattr, readBufferSize)
+		failures := batchFailures(results)
+
+		if metricsFile != "" {
+			f, err := os.Create(metricsFile)
+			if err != nil {
+				return fmt.Errorf("could not create metrics %s: %w", metricsFile, err)
+			}
+			err = newBatchMetrics(results, time.Since(start)).writePrometheus(f)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return fmt.Errorf("could not write metrics %s: %w", metricsFile, err)
+			}
+		}
+
+		w := cmd.OutOrStdout()
+		if reportFile != "" {
+			f, err := os.Create(reportFile)
+			if err != nil {
+				return fmt.Errorf("could not create report %s: %w", reportFile, err)
+			}
+			defer f.Close()
+			w = f
+		}
+
+		switch {
+		case output == "junit":
+			if err := printJUnit(w, dir, results); err != nil {
+				return fmt.Errorf("could not write report: %w", err)
+			}
+		case output != "":
+			return fmt.Errorf("invalid output %q, expected junit", output)
+		case groupKeys && jsonOutput:
+			if err := printJSON(w, groupByKey(results)); err != nil {
+				return fmt.Errorf("could not write report: %w", err)
+			}
+		case jsonOutput:
+			out := make([]jsonResult, 0, len(results))
+			for _, r := range results {
+				jr := jsonResult{result: r.result, OK: r.Err == nil}
+				if r.Err != nil {
+					jr.Error, jr.ErrorCode = r.Err.Error(), codeOf(r.Err)
+				}
+				if redactJSON {
+					jr = jr.redacted()
+				}
+				out = append(out, jr)
+			}
+			if err := printJSON(w, out); err != nil {
+				return fmt.Errorf("could not write report: %w", err)
+			}
+		case quietOnSuccess && failures == 0:
+		default:
+			styles := mustStyles()
+			cmd.Println(styles.Header.String())
+			for _, r := range results {
+				var in string
+				if v.Namespaces != nil {
+					in = " in namespace " + styles.Code.Render(r.Namespace)
+				}
+				switch {
+				case r.Err != nil:
+					cmd.Println(styles.Text.Render(
+						"Invalid signature for " +
+							styles.Code.Render(r.File) +
+							in +
+							": " +
+							r.Err.Error(),
+					))
+				case v.Namespaces != nil:
+					cmd.Println(styles.Text.Render(
+						"Valid signature for " +
+							styles.Code.Render(r.File) +
+							in +
+							".",
+					))
+				}
+			}
+			cmd.Println(styles.Text.Render(
+				"Verified " +
+					styles.Code.Render(fmt.Sprintf("%d", len(results)-failures)) +
+					" of " +
+					styles.Code.Render(fmt.Sprintf("%d", len(results))) +
+					" files in " +
+					styles.Code.Render(dir) +
+					".",
+			))
+			if groupKeys {
+				for _, g := range groupByKey(results) {
+					fp := g.Fingerprint
+					if fp == "" {
+						fp = "unknown key"
+					}
+					cmd.Println(styles.Text.Render(
+						styles.Code.Render(fp) +
+							" signed " +
+							styles.Code.Render(fmt.Sprintf("%d", g.Files)) +
+							" files, " +
+							styles.Code.Render(fmt.Sprintf("%d", g.Failed)) +
+							" failed.",
+					))
+				}
+			}
+		}
+
+		if failures > 0 {
+			return fmt.Errorf("%w: %d of %d files", errBatchFailed, failures, len(results))
+		}
+		return nil
+	}
+	verifyCmd := &cobra.Command{
+		Use:   "verify [signature]",
+		Short: "Verify a signature",
+		Example: `ssign verify README.md
+ssign verify --public-key id_ed25519.pub README.md README.md.ssig
+ssign verify --in release.tar.gz --member bin/app app.ssig
+ssign verify --manifest dist/` + manifestName + `
+ssign verify --git-range v1.0..v1.1 v1.1.patch.ssig
+ssign verify --git-object 3b18e512dba79e4c8300dd08aeb37f8e728b8dad README.md.ssig
+ssign verify --git-repo . --ref v1.2.3 --path dist/app app.ssig
+ssign verify --by-content-hash-name --sig-dir /var/lib/signatures app.tar.gz
+ssign verify --message-string hello hello.ssig
+ssign verify --receipt README.md.receipt.json
+ssign verify --bundle README.md.bundle.zip
+ssign verify --use-sidecar README.md
+ssign verify --inline README.md.signed
+ssign verify --sig-db signatures.db --sig-key app-1.0 app.tar.gz
+ssign verify --pin-from-filename --public-key release-keys.pub app.bin app.bin.SHA256-H9ZwCZ8Q2vfWl6JBJbLMq4sj2LgHMPpIsGg1LmYMDeE.ssig
+ssign verify --use-fingerprint-file app.bin
+ssign verify --combine a.bin b.bin c.bin combined.ssig
+ssign verify --reproducible-tar dist/
+ssign verify --sbom sbom.cdx.json --component app app.tar.gz
+ssign verify --filter-command 'gzip -dc' app.tar.gz app.tar.ssig
+ssign verify --vault-key-path secret/ssign/pubkey README.md
+ssign verify --ca-bundle cas.pub --principal release README.md
+ssign verify --s3 s3://releases/app.tar.gz app.tar.gz.ssig
+ssign verify --base app-1.0.bin --patch app-1.1.bsdiff app-1.1.bin.ssig
+ssign verify --silent README.md && echo trusted
+ssign verify --dry-verify --namespace-map namespaces.json dist/
+ssign verify --output junit --report-file report.xml dist/
+ssign verify --namespace-map namespaces.json dist/`,
+		Aliases: []string{"v"},
+		Args: func(cmd *cobra.Command, args []string) error {
+			err := verifyArgs(cmd, args)
+			if err == nil && silent {
+				// cobra validates the flag groups after the arguments, but
+				// before the errors could be silenced.
+				err = cmd.ValidateFlagGroups()
+			}
+			return silence(err, silent)
+		},
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			if silent {
+				cmd.SetOut(io.Discard)
+				cmd.SetErr(io.Discard)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			defer func() { err = silence(err, silent) }()
+			if jsonOutput {
+				defer func() {
+					file := receiptPath + bundlePath
+					if len(args) > 0 {
+						file = args[0]
+					}
+					if err != nil && !errors.Is(err, errBatchFailed) {
+						printJSONError(cmd.OutOrStdout(), result{File: file, Key: pubkeyPath, Namespace: namespace}, err, redactJSON)
+					}
+				}()
+			}
+
+			var rcpt receipt
+			if receiptPath != "" {
+				rcpt, err = readReceipt(receiptPath)
+				if err != nil {
+					return fmt.Errorf("could not read receipt %s: %w", receiptPath, err)
+				}
+				if cmd.Flags().Changed("namespace") && namespace != rcpt.Namespace {
+					return fmt.Errorf("%w: the signature was made in namespace %q, not %q", errReceiptMismatch, rcpt.Namespace, namespace)
+				}
+				namespace = rcpt.Namespace
+				args = []string{rcpt.File, rcpt.Signature}
+			}
+			var meta sidecar
+			if useSidecar {
+				if len(args) > 1 {
+					return fmt.Errorf("--use-sidecar only takes the file, the signature path is in its sidecar")
+				}
+				meta, err = readSidecar(args[0])
+				if err != nil {
+					return fmt.Errorf("could not read sidecar %s: %w", args[0]+sidecarSuffix, err)
+				}
+				if cmd.Flags().Changed("namespace") && namespace != meta.Namespace {
+					return fmt.Errorf("%w: the signature was made in namespace %q, not %q", errSidecarMismatch, meta.Namespace, namespace)
+				}
+				namespace = meta.Namespace
+				args = []string{args[0], meta.Signature}
+			}
+			var fprName, fprPin string
+			switch {
+			case useFingerprintFile:
+				fprName = args[0] + fingerprintFileSuffix
+			case fingerprintFile != "":
+				fprName = fingerprintFile
+			}
+			if fprName != "" {
+				fprPin, err = readFingerprintFile(fprName)
+				if err != nil {
+					return fmt.Errorf("could not read fingerprint file %s: %w", fprName, err)
+				}
+			}
+			var bndl bundle
+			if bundlePath != "" {
+				bndl, err = readBundle(bundlePath)
+				if err != nil {
+					return fmt.Errorf("could not read bundle %s: %w", bundlePath, err)
+				}
+				args = []string{bundlePath}
+			}
+
+			fields, err := parseAttestations(attest)
+			if err != nil {
+				return err
+			}
+			pol.Namespace = namespace
+			pol.CheckReserved = cmd.Flags().Changed("expect-reserved")
+			if pol.AllowSHA1 {
+				cmd.PrintErrln("Warning: --allow-sha1 accepts ssh-rsa signatures, whose SHA-1 hash is vulnerable to collisions, so they can be forged. It is deprecated and only meant for legacy signatures, which should be made again with rsa-sha2-512 or an Ed25519 key.")
+			}
+			// sha256 signatures are valid SSHSIG, but can't be verified, so
+			// requiring them would reject every signature.
+			if h := pol.RequireHash; h != "" && h != "sha512" {
+				return fmt.Errorf("invalid --require-hash %q, only sha512 signatures can be verified", h)
+			}
+			if err := checkUnicodeForm(unicodeForm); err != nil {
+				return err
+			}
+			v := verifier{
+				KeyName:       pubkeyPath,
+				Namespace:     namespace,
+				Policy:        pol,
+				StripBOM:      stripBOMs,
+				CRLF:          crlf,
+				UnicodeForm:   unicodeForm,
+				JSONCanonical: jsonCanonical,
+				JCS:           jcs,
+				JSONPointer:   jsonPointer,
+				Attestations:  fields,
+				DNSIdentity:   dnsIdentity,
+			}
+			if maxSignatureAge > 0 {
+				v.MaxSignatureAge = maxSignatureAge
+			}
+			var signer sbomSigner
+			if sbomPath != "" {
+				signer, err = readSBOMSigner(sbomPath, sbomComponent)
+				if err != nil {
+					return fmt.Errorf("could not read SBOM %s: %w", sbomPath, err)
+				}
+			}
+			if caPrincipal != "" && caBundle == "" {
+				return fmt.Errorf("--principal requires --ca-bundle")
+			}
+			var agentKeys []*agent.Key
+			var cas []ssh.PublicKey
+			switch {
+			case sbomPath != "":
+				// the embedded key is used, and checked against the declared
+				// fingerprint once verified, so a signature by another key is
+				// reported as not matching the SBOM.
+			case caBundle != "":
+				cas, err = openPublicKeys(caBundle)
+				if err != nil {
+					return fmt.Errorf("could not parse CA bundle %s: %w", caBundle, withCode(codeKey, err))
+				}
+				// the certificate embedded in the signature is used, and
+				// checked against the CAs once verified.
+			case useAgent:
+				agentKeys, err = openAgentPublicKeys()
+				if err != nil {
+					return fmt.Errorf("could not list the agent keys: %w", withCode(codeKey, err))
+				}
+				// the signature must still match one of the keys, the key
+				// embedded in it is never trusted on its own.
+				v.Pubs = make([]ssh.PublicKey, 0, len(agentKeys))
+				for _, key := range agentKeys {
+					v.Pubs = append(v.Pubs, key)
+				}
+				v.KeyName = "the SSH agent"
+			case vaultKeyPath != "":
+				v.Pubs, err = openVaultPublicKeys(cmd.Context(), vaultKeyPath, vaultCacheTTL)
+				if err != nil {
+					return fmt.Errorf("could not fetch public key %s from Vault: %w", vaultKeyPath, withCode(codeKey, err))
+				}
+				v.KeyName = "Vault " + vaultKeyPath
+			case pkcs11Module != "":
+				if pkcs11Label == "" {
+					return fmt.Errorf("--pkcs11 requires --pkcs11-label")
+				}
+				pub, err := openPKCS11PublicKey(pkcs11Module, pkcs11Label)
+				if err != nil {
+					return fmt.Errorf("could not load public key %s from PKCS#11: %w", pkcs11Label, withCode(codeKey, err))
+				}
+				v.Pubs = []ssh.PublicKey{pub}
+				v.KeyName = "PKCS#11 " + pkcs11Label + " " + ssh.FingerprintSHA256(pub)
+			case knownHostsFile != "":
+				v.Pubs, err = openKnownHostKeys(knownHostsFile, knownHost)
+				if err != nil {
+					return fmt.Errorf("could not find the key of %s in %s: %w", knownHost, knownHostsFile, withCode(codeKey, err))
+				}
+				v.KeyName = "host key of " + knownHost + " in " + knownHostsFile
+			case fprName != "" && bndl.PublicKey == nil && dnsIdentity == "" && !confirmFingerprint && !trustEmbeddedKey && !cmd.Flags().Changed("public-key"):
+				// the embedded key is used, and checked against the key the
+				// fingerprint file pins once verified.
+				if useFingerprintFile {
+					defer cmd.PrintErrln("Warning: the embedded key was only checked against " + fprName + ", which whoever publishes the signature can publish too, use --public-key, or --fingerprint-file with a fingerprint got through another channel, to verify with a key you trust.")
+				}
+			case bndl.PublicKey != nil && dnsIdentity == "" && !confirmFingerprint && !trustEmbeddedKey && !cmd.Flags().Changed("public-key"):
+				pub, _, _, _, err := ssh.ParseAuthorizedKey(bndl.PublicKey)
+				if err != nil {
+					return fmt.Errorf("could not parse the public key of bundle %s: %w", bundlePath, withCode(codeKey, err))
+				}
+				v.Pubs = []ssh.PublicKey{pub}
+				v.KeyName = bundlePath + ":" + bndl.Name + ".pub"
+				defer cmd.PrintErrln("Warning: the key was read from the bundle, it is only as trustworthy as where the bundle came from, use --public-key to verify with a key you trust.")
+			case (dnsIdentity == "" && !confirmFingerprint && !trustEmbeddedKey) || cmd.Flags().Changed("public-key"):
+				v.Pubs, err = openPublicKeys(pubkeyPath)
+				if err != nil {
+					return fmt.Errorf("could not parse public key %s: %w", pubkeyPath, withCode(codeKey, err))
+				}
+				if honorKeyExpiry {
+					v.KeyExpiries, err = openKeyExpiries(pubkeyPath)
+					if err != nil {
+						return fmt.Errorf("could not parse public key %s: %w", pubkeyPath, withCode(codeKey, err))
+					}
+				}
+			}
+			if honorKeyExpiry && v.KeyExpiries == nil {
+				return fmt.Errorf("--honor-key-expiry requires the keys to be read from --public-key")
+			}
+			if len(keyIDs) > 0 {
+				v.KeyIDs, err = labelKeys(v.Pubs, keyIDs)
+				if err != nil {
+					return fmt.Errorf("invalid --key-id: %w", err)
+				}
+			}
+			if pol.KeyAlgorithm != "" && len(v.Pubs) > 0 {
+				// only the keys of the required algorithm are tried, so the
+				// others can't be used to verify.
+				pubs := keysWithAlgorithm(v.Pubs, pol.KeyAlgorithm)
+				if len(pubs) == 0 {
+					return fmt.Errorf("could not verify: %w", withCode(codeKey, fmt.Errorf("%s has no %s key", v.KeyName, pol.KeyAlgorithm)))
+				}
+				v.Pubs = pubs
+			}
+			if dnsIdentity != "" {
+				defer cmd.PrintErrln("Warning: ssign does not validate DNSSEC, the DNS identity is only as trustworthy as your resolver.")
+			}
+
+			if info, err := os.Stat(args[0]); err == nil && info.IsDir() && gitRef == "" && gitPath == "" && !combine && !reproTar && !inline && sigDB == "" && filterCommand == "" && bundlePath == "" && patchPath == "" && archivePath == "" && gitRange == "" && gitObject == "" && !cmd.Flags().Changed("message-string") && s3URL == "" && gcsURL == "" {
+				if len(args) > 1 {
+					return fmt.Errorf("cannot use a signature path when verifying a directory")
+				}
+				if sbomPath != "" {
+					return fmt.Errorf("--sbom cannot be used with a directory")
+				}
+				if pinFromFilename {
+					return fmt.Errorf("--pin-from-filename cannot be used with a directory")
+				}
+				if fprName != "" {
+					return fmt.Errorf("--use-fingerprint-file and --fingerprint-file cannot be used with a directory")
+				}
+				if caBundle != "" {
+					return fmt.Errorf("--ca-bundle cannot be used with a directory")
+				}
+				if readAheadFiles < 0 {
+					return fmt.Errorf("invalid --read-ahead %d, expected 0 or more files", readAheadFiles)
+				}
+				v.ReadAhead = readAheadFiles
+				if namespaceMapPath != "" {
+					nsMap, err := readNamespaceMap(namespaceMapPath, args[0])
+					if err != nil {
+						return fmt.Errorf("could not read namespace map %s: %w", namespaceMapPath, err)
+					}
+					v.Namespaces = &nsMap
+				}
+				return runBatchVerify(cmd, v, args[0])
+			}
+			if namespaceMapPath != "" {
+				return fmt.Errorf("--namespace-map requires a directory")
+			}
+			if readAheadFiles != 0 {
+				return fmt.Errorf("--read-ahead requires a directory")
+			}
+			if output != "" {
+				return fmt.Errorf("--output %s requires a directory", output)
+			}
+			if metricsFile != "" {
+				return fmt.Errorf("--metrics requires a directory")
+			}
+			if groupKeys {
+				return fmt.Errorf("--group-by-key requires a directory")
+			}
+			useMessageString := cmd.Flags().Changed("message-string")
+			objectURL := s3URL + gcsURL
+			useGitRef := gitRef != "" || gitPath != ""
+			if useGitRef && len(args) > 1 {
+				return fmt.Errorf("--ref and --path only take the signature path")
+			}
+			manifest = manifest || manifestSignatureOnly
+			if inline && len(args) > 1 {
+				return fmt.Errorf("--inline only takes the inline signed file")
+			}
+			if sigDB != "" && len(args) > 1 {
+				return fmt.Errorf("--sig-db only takes the file, and cannot be used with a signature path")
+			}
+			if reproTar {
+				// so the signature defaults to dir.ssig, not dir/.ssig.
+				args[0] = filepath.Clean(args[0])
+			}
+			usePatch := basePath != "" || patchPath != ""
+			if usePatch && len(args) > 1 {
+				return fmt.Errorf("--base and --patch only take the signature path")
+			}
+
+			subject := args[0]
+			var message, inlineSignature []byte
+			switch {
+			case inline:
+				data, err := readFile(args[0], readBufferSize)
+				if err != nil {
+					return fmt.Errorf("could not open subject: %w", err)
+				}
+				message, inlineSignature, err = splitInline(data)
+				if err != nil {
+					return fmt.Errorf("could not read %s: %w", args[0], err)
+				}
+			case combine:
+				subject = strings.Join(args[:len(args)-1], ", ")
+				message, err = combinedMessage(args[:len(args)-1], readBufferSize)
+				if err != nil {
+					return fmt.Errorf("could not verify: %w", err)
+				}
+			case reproTar:
+				message, err = reproducibleTar(args[0])
+				if err != nil {
+					return fmt.Errorf("could not verify %s: %w", args[0], err)
+				}
+			case usePatch:
+				subject = basePath + " patched with " + patchPath
+				message, err = applyPatch(basePath, patchPath)
+				if err != nil {
+					return fmt.Errorf("could not apply patch %s to %s: %w", patchPath, basePath, err)
+				}
+			case bundlePath != "":
+				subject = bundlePath + ":" + bndl.Name
+				message = bndl.Content
+			case useMessageString:
+				if len(args) > 1 {
+					return fmt.Errorf("--message-string only takes the signature path")
+				}
+				if exists(messageString) {
+					cmd.PrintErrf("Warning: verifying the text %q itself, not the file with that name.\n", messageString)
+				}
+				subject = "message string"
+				message = []byte(messageString)
+			case objectURL != "":
+				if len(args) > 1 {
+					return fmt.Errorf("--s3 and --gcs only take the signature path")
+				}
+				if (s3URL != "" && !strings.HasPrefix(s3URL, "s3://")) || (gcsURL != "" && !strings.HasPrefix(gcsURL, "gs://")) {
+					return fmt.Errorf("invalid object URL %q, expected s3://bucket/key or gs://bucket/object", objectURL)
+				}
+				subject = objectURL
+				message, err = readObject(cmd.Context(), objectURL, readBufferSize)
+				if err != nil {
+					return fmt.Errorf("could not read %s: %w", subject, err)
+				}
+			case archivePath != "":
+				if member == "" {
+					return fmt.Errorf("--in requires --member")
+				}
+				if len(args) > 1 {
+					return fmt.Errorf("--in only takes the signature path")
+				}
+				subject = archivePath + ":" + member
+				message, err = readArchiveMember(archivePath, member)
+				if err != nil {
+					return fmt.Errorf("could not read %s: %w", subject, err)
+				}
+			case gitRange != "":
+				if len(args) > 1 {
+					return fmt.Errorf("--git-range only takes the signature path")
+				}
+				subject = "git " + gitFormat + " " + gitRange
+				message, err = gitRangeContent(gitRange, gitFormat)
+				if err != nil {
+					return fmt.Errorf("could not generate %s: %w", subject, err)
+				}
+			case gitObject != "":
+				if len(args) > 1 {
+					return fmt.Errorf("--git-object only takes the signature path")
+				}
+				var oid string
+				oid, message, err = gitObjectContent("", gitObject)
+				if err != nil {
+					return fmt.Errorf("could not read git object %s: %w", gitObject, err)
+				}
+				subject = "git object " + oid
+			case useGitRef:
+				subject = gitPath + " at " + gitRef + " in " + gitRepo
+				_, message, err = gitFileAtRef(gitRepo, gitRef, gitPath)
+				if err != nil {
+					return fmt.Errorf("could not read %s: %w", subject, err)
+				}
+			case filterCommand != "":
+				message, err = filterFile(args[0], filterCommand)
+				if err != nil {
+					return fmt.Errorf("could not filter %s: %w", args[0], err)
+				}
+			default:
+				message, err = readFile(args[0], readBufferSize)
+				if err != nil {
+					return fmt.Errorf("could not open subject: %w", err)
+				}
+				if receiptPath != "" {
+					if err := rcpt.checkContent(message); err != nil {
+						return fmt.Errorf("could not verify: %w", err)
+					}
+				}
+			}
+
+			var sigName string
+			var signature []byte
+			if bundlePath != "" {
+				sigName = bundlePath + ":" + bndl.Name + ".ssig"
+				signature = bndl.Signature
+			} else if inline {
+				sigName = args[0]
+				signature = inlineSignature
+			} else if sigDB != "" {
+				sigName = sigDB + ":" + sigDBKey
+				signature, err = readDBSignature(sigDB, sigDBKey)
+				if err != nil {
+					return fmt.Errorf("could not read signature from %s: %w", sigDB, err)
+				}
+			} else if archivePath != "" || gitRange != "" || gitObject != "" || useMessageString || objectURL != "" || usePatch || combine || useGitRef {
+				sigName = args[len(args)-1]
+				signature, err = os.ReadFile(sigName)
+				if err != nil {
+					return fmt.Errorf("could not open signature: %w", err)
+				}
+			} else {
+				switch {
+				case byContentHash:
+					if len(args) > 1 || useXattr {
+						return fmt.Errorf("cannot use a signature path or --xattr with --by-content-hash-name")
+					}
+					sigName = contentHashSignature(sigDir, message)
+				case len(args) > 1:
+					sigName = args[1]
+				}
+				sigName, signature, err = readSignature(args[0], sigName, useXattr)
+				if err != nil && lenientUnsigned && isUnsigned(err) {
+					warning := subject + " is not signed, allowed by --lenient-unsigned"
+					if jsonOutput {
+						jr := jsonResult{result: result{File: subject, Key: v.KeyName, Namespace: namespace}, OK: true, Warning: warning}
+						if redactJSON {
+							jr = jr.redacted()
+						}
+						return printJSON(cmd.OutOrStdout(), jr)
+					}
+					styles := mustStyles()
+					cmd.Println(styles.Header.String())
+					cmd.Println(styles.Text.Render("Warning: " + warning + "."))
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+			}
+
+			if dryVerify {
+				plan := v.plan(subject, sigName)
+				if jsonOutput {
+					if redactJSON {
+						plan = jsonResult{result: plan}.redacted().result
+					}
+					return printJSON(cmd.OutOrStdout(), plan)
+				}
+				printPlans(cmd, []result{plan})
+				return nil
+			}
+			res, err := v.verify(subject, message, sigName, signature)
+			if err != nil && explain {
+				printExplanation(cmd, v.explain(message, signature))
+			}
+			for p := newPrompter(cmd); err != nil && pauseOnError && p.interactive(); {
+				action, perr := askFailureAction(p, err)
+				if perr != nil {
+					return perr
+				}
+				switch action {
+				case "explain":
+					printExplanation(cmd, v.explain(message, signature))
+					continue
+				case "retry":
+					name, perr := askPublicKey(p)
+					if perr != nil {
+						return perr
+					}
+					pubs, perr := openPublicKeys(name)
+					if perr != nil {
+						cmd.PrintErrf("Could not parse public key %s: %v\n", name, perr)
+						continue
+					}
+					v.Pubs, v.KeyName = pubs, name
+					res, err = v.verify(subject, message, sigName, signature)
+					continue
+				}
+				break
+			}
+			if err != nil && compatKeygen {
+				compareKeygen(cmd, v, message, signature, err)
+			}
+			if err != nil {
+				return err
+			}
+			if useAgent && v.KeyName == "the SSH agent" {
+				res.Key = agentKeyName(agentKeys, res.Fingerprint)
+			}
+			if receiptPath != "" {
+				if err := rcpt.checkResult(res); err != nil {
+					return fmt.Errorf("could not verify: %w", err)
+				}
+			}
+			if useSidecar {
+				if err := meta.checkResult(res); err != nil {
+					return fmt.Errorf("could not verify: %w", err)
+				}
+			}
+			if sbomPath != "" {
+				if err := signer.checkResult(res); err != nil {
+					return fmt.Errorf("could not verify: %w", err)
+				}
+				res.Key = "component " + sbomComponent + " of " + sbomPath
+			}
+			if pinFromFilename {
+				if err := checkFilenamePin(res); err != nil {
+					return fmt.Errorf("could not verify: %w", err)
+				}
+			}
+			if fprName != "" {
+				if err := checkFingerprintFile(res, fprPin, fprName); err != nil {
+					return fmt.Errorf("could not verify: %w", err)
+				}
+			}
+			if caBundle != "" {
+				sig, err := parseSignature(signature)
+				if err != nil {
+					return fmt.Errorf("could not parse signature %s: %w", sigName, withCode(codeInvalidSignature, err))
+				}
+				match, err := checkCertificate(sig.PublicKey, cas, caPrincipal, time.Now())
+				if err != nil {
+					return fmt.Errorf("could not verify: %w", err)
+				}
+				res.Key = fmt.Sprintf("certificate %q of %s, issued by CA %s of %s", match.Cert.KeyId, match.Principal, match.CA, caBundle)
+			}
+			var keyExpires string
+			if exp, ok := v.KeyExpiries[res.Fingerprint]; ok {
+				keyExpires = exp.Date
+			}
+			var sigAge string
+			if maxSignatureAge > 0 {
+				age, err := checkSignatureAge(sigName, maxSignatureAge)
+				if err != nil {
+					return fmt.Errorf("could not verify: %w", err)
+				}
+				sigAge = formatAge(age)
+			}
+			if len(v.Pubs) == 0 && (confirmFingerprint || trustEmbeddedKey) {
+				sig, err := parseSignature(signature)
+				if err != nil {
+					return fmt.Errorf("could not parse signature %s: %w", sigName, err)
+				}
+				if err := confirmKey(newPrompter(cmd), sig.PublicKey, trustEmbeddedKey); err != nil {
+					return err
+				}
+			}
+
+			var warning string
+			if filterCommand != "" {
+				warning = "the signature covers the output of " + filterCommand + ", not " + subject + " itself"
+			}
+			if jsonPointer != "" {
+				if warning != "" {
+					warning += ", and "
+				}
+				warning += "the signature only covers " + jsonPointer + " of " + subject + ", not the rest of the document"
+			}
+			if manifestSignatureOnly {
+				warning = "only the manifest signature was verified, the contents of the listed files were NOT checked"
+			} else if manifest {
+				entries, err := parseManifest(message)
+				if err != nil {
+					return fmt.Errorf("could not parse manifest %s: %w", subject, err)
+				}
+				if err := checkManifestOrder(entries, sortOrder); err != nil {
+					return fmt.Errorf("could not verify manifest %s: %w", subject, err)
+				}
+				if err := checkManifest(subject, sigName, entries, exactManifest); err != nil {
+					return fmt.Errorf("could not verify manifest %s: %w", subject, err)
+				}
+			}
+
+			if jsonOutput {
+				jr := jsonResult{result: res, OK: true, Warning: warning, SignatureAge: sigAge, KeyExpires: keyExpires}
+				if redactJSON {
+					jr = jr.redacted()
+				}
+				return printJSON(cmd.OutOrStdout(), jr)
+			}
+			if outputTemplate != "" {
+				return printTemplate(cmd.OutOrStdout(), outputTemplate, res)
+			}
+			if quietOnSuccess {
+				if warning != "" {
+					cmd.PrintErrln("Warning: " + warning + ".")
+				}
+				return nil
+			}
+
+			styles := mustStyles()
+			cmd.Println(styles.Header.String())
+			cmd.Println(styles.Text.Render(
+				"Valid signature for " +
+					styles.Code.Render(res.File) +
+					" at " +
+					styles.Code.Render(res.Signature) +
+					".",
+			))
+			keyName := styles.Code.Render(res.Key)
+			if res.KeyID != "" {
+				keyName = styles.Code.Render(res.KeyID) + " from " + keyName
+			}
+			cmd.Println(styles.Text.Render(
+				"Verified signed for key " +
+					keyName +
+					".",
+			))
+			if dnsIdentity != "" {
+				cmd.Println(styles.Text.Render(
+					"Key published by " +
+						styles.Code.Render(dnsIdentity) +
+						".",
+				))
+			}
+			if manifest && !manifestSignatureOnly {
+				cmd.Println(styles.Text.Render("All files match the manifest."))
+			}
+			if keyExpires != "" {
+				cmd.Println(styles.Text.Render(
+					"Key valid until " +
+						styles.Code.Render(keyExpires) +
+						".",
+				))
+			}
+			if sigAge != "" {
+				cmd.Println(styles.Text.Render(
+					"Signature file modified " +
+						styles.Code.Render(sigAge) +
+						" ago.",
+				))
+			}
+			if warning != "" {
+				cmd.Println(styles.Text.Render("Warning: " + warning + "."))
+			}
+			return nil
+		},
+	}
+	verifyCmd.PersistentFlags().StringVar(&pubkeyPath, "public-key", os.ExpandEnv("$HOME/.ssh/id_ed25519.pub"), "SSH public key to be used")
+	verifyCmd.PersistentFlags().BoolVar(&useAgent, "agent", false, "Verify with any of the keys loaded in the SSH agent, reporting which one matched")
+	verifyCmd.PersistentFlags().StringArrayVar(&keyIDs, "key-id", nil, "Label of the trusted keys, in the order they are loaded, e.g. the lines of --public-key, reported with the key that made the signature, in the output, --json, and --template, can be repeated")
+	verifyCmd.PersistentFlags().StringVar(&caBundle, "ca-bundle", "", "Verify with the certificate embedded in the signature, requiring it to be a valid user certificate issued by any of the CA keys in this file, in the authorized_keys format, instead of using --public-key")
+	verifyCmd.PersistentFlags().StringVar(&caPrincipal, "principal", "", "Principal the --ca-bundle certificate must be valid for (any of its principals by default)")
+	verifyCmd.PersistentFlags().StringVar(&vaultKeyPath, "vault-key-path", "", "Path of a HashiCorp Vault secret whose public_key field has the trusted public keys, in the authorized_keys format, instead of --public-key, with the Vault address and token from VAULT_ADDR and VAULT_TOKEN (requires building with -tags vault)")
+	verifyCmd.PersistentFlags().DurationVar(&vaultCacheTTL, "vault-cache-ttl", 5*time.Minute, "How long the keys fetched from Vault are cached, so rotated keys are picked up after at most that long (0 to always fetch them)")
+	verifyCmd.PersistentFlags().StringVar(&pkcs11Module, "pkcs11", "", "Path of a PKCS#11 module to load the public key from a token instead of --public-key (requires building with -tags pkcs11)")
+	verifyCmd.PersistentFlags().StringVar(&pkcs11Label, "pkcs11-label", "", "Label of the public key on the --pkcs11 token")
+	verifyCmd.PersistentFlags().BoolVar(&confirmFingerprint, "confirm-fingerprint", false, "Verify with the key embedded in the signature, asking to trust it if it's not a known key (known keys are kept in the ssign/known_keys file of the user config directory)")
+	verifyCmd.PersistentFlags().BoolVar(&trustEmbeddedKey, "trust-embedded-key", false, "Verify with the key embedded in the signature, trusting it without asking")
+	verifyCmd.PersistentFlags().BoolVar(&honorKeyExpiry, "honor-key-expiry", false, "Reject signatures of the --public-key keys whose comment has an \"expires=2025-12-31\" date (or RFC 3339 time) that is past, the key being valid until the end of that day, in UTC")
+	verifyCmd.PersistentFlags().StringVar(&knownHostsFile, "known-hosts", "", "Verify with the host keys of --host recorded in this known_hosts file instead of --public-key")
+	verifyCmd.PersistentFlags().StringVar(&knownHost, "host", "", "Host, or host:port, whose key in --known-hosts made the signature")
+	verifyCmd.PersistentFlags().StringVar(&namespace, "namespace", defaultNamespace, "Namespace the signature must have been made in")
+	verifyCmd.PersistentFlags().StringVar(&namespaceMapPath, "namespace-map", "", "When verifying a directory, JSON object mapping file patterns (e.g. \"*.tar.gz\" or \"docs/*\") to the namespace the matching files were signed in, the first matching pattern wins and the others use --namespace")
+	verifyCmd.PersistentFlags().Var(&maxSignatureAge, "max-signature-age", "Reject signature files last modified longer ago than this, e.g. 30d or 12h. The modification time is easily changed by anyone who can write the file: this is a freshness convenience, not a security control")
+	verifyCmd.PersistentFlags().BoolVar(&pol.StrictNamespace, "expected-namespace-strict", false, "Fail with a dedicated error when the signature namespace differs from --namespace")
+	verifyCmd.PersistentFlags().StringArrayVar(&pol.DeprecatedNamespaces, "deprecated-namespace", nil, "Reject signatures made under this namespace, even if they are valid, can be repeated")
+	verifyCmd.PersistentFlags().BoolVar(&pol.AllowSHA1, "allow-sha1", false, "Accept ssh-rsa signatures, which use SHA-1 and are rejected by default: SHA-1 collisions make them forgeable, so this is a deprecated escape hatch for legacy signatures, printing a warning every time")
+	verifyCmd.PersistentFlags().BoolVar(&pol.FIPS, "fips", false, "Reject signatures made with algorithms outside of --fips-algorithms")
+	verifyCmd.PersistentFlags().StringVar(&pol.RequireHash, "require-hash", "", "Reject signatures whose message hash algorithm is not this one, reporting the one they use: only sha512 is accepted, as it's the only one verify supports")
+	verifyCmd.PersistentFlags().StringVar(&pol.KeyAlgorithm, "key-algorithm", "", "Only accept signatures made with a key of this algorithm, e.g. ssh-ed25519, and only try the given keys of that algorithm")
+	verifyCmd.PersistentFlags().StringVar(&pol.Reserved, "expect-reserved", "", "Reject signatures whose reserved field, as shown by inspect, is not this value (empty by default, as written by OpenSSH). The field is not covered by the signature, so anyone can change it: this checks the format of an extension, not who set it")
+	verifyCmd.PersistentFlags().IntVar(&pol.MinRSABits, "min-rsa-bits", 0, "Reject signatures made with RSA keys smaller than this many bits")
+	verifyCmd.PersistentFlags().StringSliceVar(&pol.FIPSAlgorithms, "fips-algorithms", fipsAlgorithms, "Signature algorithms accepted by --fips (ssh-ed25519 is approved by FIPS 186-5, but not allowed by default)")
+	verifyCmd.PersistentFlags().BoolVar(&stripBOMs, "strip-bom", false, "Verify the file without its leading UTF-8 byte order mark, if any, e.g. when it was added by a Windows editor after signing")
+	verifyCmd.PersistentFlags().BoolVar(&crlf, "canonicalize-newlines-to-crlf", false, "Verify the file with its LF line endings converted to CRLF, for signatures made with \"ssign sign --canonicalize-newlines-to-crlf\"")
+	verifyCmd.PersistentFlags().StringVar(&unicodeForm, "normalize-unicode", "", "Verify the text with its Unicode normalized to this form, nfc or nfd, for signatures made with \"ssign sign --normalize-unicode\"")
+	verifyCmd.PersistentFlags().BoolVar(&jsonCanonical, "json-canonical", false, "Verify against the canonical form of a JSON file (sorted keys, no whitespace) instead of its raw bytes")
+	verifyCmd.PersistentFlags().BoolVar(&jcs, "jcs", false, "Verify against the RFC 8785 canonical form (JSON Canonicalization Scheme) of a JSON file instead of its raw bytes, for signatures made by any JCS implementation")
+	verifyCmd.PersistentFlags().StringVar(&jsonPointer, "json-pointer", "", "Only verify the value this JSON pointer, e.g. /spec/template, selects in the JSON file, in canonical form, for signatures made with \"ssign sign --json-pointer\"")
+	verifyCmd.PersistentFlags().StringArrayVar(&attest, "attest", nil, "Attestation field (key=value) that must have been signed together with the file, can be repeated")
+	verifyCmd.PersistentFlags().BoolVar(&useXattr, "xattr", false, "Read the signature from the "+signatureXattr+" extended attribute of the file instead of a sidecar file")
+	verifyCmd.PersistentFlags().BoolVar(&byContentHash, "by-content-hash-name", false, "Read the signature from <sha256 of the file>.ssig in --sig-dir")
+	verifyCmd.PersistentFlags().StringVar(&sigDir, "sig-dir", ".", "Directory holding the signatures named by content hash")
+	verifyCmd.PersistentFlags().StringVar(&messageString, "message-string", "", "Verify this text instead of a file, the only argument is then the signature path")
+	verifyCmd.PersistentFlags().StringVar(&s3URL, "s3", "", "Verify the s3://bucket/key object instead of a file, using the AWS credential chain (requires building with -tags s3)")
+	verifyCmd.PersistentFlags().StringVar(&gcsURL, "gcs", "", "Verify the gs://bucket/object object instead of a file, using the Google application default credentials (requires building with -tags gcs)")
+	verifyCmd.PersistentFlags().StringVar(&filterCommand, "filter-command", "", "Run the file through this command, e.g. 'gzip -dc', and verify its output instead: the signature must cover the filtered bytes, not the file")
+	verifyCmd.PersistentFlags().StringVar(&basePath, "base", "", "Verify the result of applying --patch to this file instead of a file, the only argument is then the signature path")
+	verifyCmd.PersistentFlags().StringVar(&patchPath, "patch", "", "Binary patch to apply to --base, in the bsdiff 4 format (starting with BSDIFF40, as written by bsdiff)")
+	verifyCmd.PersistentFlags().StringVar(&archivePath, "in", "", "Read the subject from a tar, tar.gz, or zip archive instead of a file")
+	verifyCmd.PersistentFlags().StringVar(&member, "member", "", "Path of the subject within the --in archive")
+	verifyCmd.PersistentFlags().StringVar(&gitRange, "git-range", "", "Verify the changes in this range of the current git repository instead of a file, generated again the same way as \"ssign sign --git-range\" (needs the same commits and a git version producing the same diffs)")
+	verifyCmd.PersistentFlags().BoolVar(&useFingerprintFile, "use-fingerprint-file", false, "Require the signing key to be the one whose fingerprint is in the file name plus .fpr, as written by \"ssign sign --emit-fingerprint-file\", verifying with the key embedded in the signature unless --public-key is set (the fingerprint file is then only as trustworthy as where it came from, prefer --fingerprint-file with one got through another channel)")
+	verifyCmd.PersistentFlags().StringVar(&fingerprintFile, "fingerprint-file", "", "Like --use-fingerprint-file, with the fingerprint read from this file instead of the one next to the signed file, e.g. one got from the signer through a channel you trust")
+	verifyCmd.PersistentFlags().BoolVar(&pinFromFilename, "pin-from-filename", false, "Require the signing key to be the one pinned by the signature file name, e.g. app.bin.SHA256-<hash>.ssig with the hash of its fingerprint in URL-safe base64, among the --public-key keys (the name is chosen by whoever publishes the signature, so it only selects a trusted key, it does not make one)")
+	verifyCmd.PersistentFlags().BoolVar(&dryVerify, "dry-verify", false, "Only print the file, signature, key, and namespace each verification would use, without verifying anything, to debug how the paths and flags are resolved")
+	verifyCmd.PersistentFlags().BoolVar(&silent, "silent", false, "Print nothing at all, not even the errors and warnings, the exit status being the only result, e.g. for \"if ssign verify --silent ...; then\" (drop it to see why a verification fails)")
+	// the flags are parsed before --silent is set, so it's looked for in
+	// the arguments to silence the errors of parsing them.
+	verifyCmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return silence(err, silentArg(os.Args[1:]))
+	})
+	verifyCmd.PersistentFlags().BoolVar(&quietOnSuccess, "quiet-on-success-only", false, "Print nothing when the verification succeeds, except for warnings on stderr, and the full diagnostics when it fails, e.g. to keep CI logs clean (the exit status is unchanged)")
+	verifyCmd.PersistentFlags().BoolVar(&inline, "inline", false, "Verify a file followed by its signature, as written by \"ssign sign --both\", instead of a file and a separate signature")
+	verifyCmd.PersistentFlags().BoolVar(&reproTar, "reproducible-tar", false, "Verify a directory signed with \"ssign sign --reproducible-tar\", generating the same normalized tar stream of its content, against the directory name plus .ssig or the given signature")
+	verifyCmd.PersistentFlags().BoolVar(&combine, "combine", false, "Verify the given files together, in the order they were signed with \"ssign sign --combine\", against a single signature, the last argument")
+	verifyCmd.PersistentFlags().StringVar(&sigDB, "sig-db", "", "Read the signature from the \""+sigDBBucket+"\" bucket of this bolt database instead of a file")
+	verifyCmd.PersistentFlags().StringVar(&sigDBKey, "sig-key", "", "Key of the signature in the --sig-db bucket")
+	verifyCmd.PersistentFlags().BoolVar(&useSidecar, "use-sidecar", false, "Read the signature path (relative to it), key fingerprint, and namespace from the file's \""+sidecarSuffix+"\" sidecar, e.g. README.md"+sidecarSuffix+", checking the key fingerprint matches it")
+	verifyCmd.PersistentFlags().StringVar(&sbomPath, "sbom", "", "Verify that the file was signed by the key the CycloneDX JSON SBOM declares for --component, in its \"ssign:public-key\" (authorized_keys format) or \"ssign:fingerprint\" property, instead of using --public-key")
+	verifyCmd.PersistentFlags().StringVar(&sbomComponent, "component", "", "Name of the --sbom component the file is")
+	verifyCmd.PersistentFlags().StringVar(&receiptPath, "receipt", "", "Verify the file and signature recorded in this receipt of \"ssign sign --receipt\", checking the file SHA256 and the key fingerprint match it, instead of taking them as arguments")
+	verifyCmd.PersistentFlags().StringVar(&bundlePath, "bundle", "", "Verify the file and signature in this bundle of \"ssign bundle\", a tar.gz or zip archive detected by its content, with the public key in it unless --public-key is set")
+	verifyCmd.PersistentFlags().StringVar(&gitRepo, "git-repo", ".", "Git repository to read --path from at --ref")
+	verifyCmd.PersistentFlags().StringVar(&gitRef, "ref", "", "Verify the file at --path as committed at this ref (a tag, branch, or commit) of --git-repo instead of a file, the only argument is then the signature path")
+	verifyCmd.PersistentFlags().StringVar(&gitPath, "path", "", "Path of the file to verify at --ref, relative to the root of --git-repo")
+	verifyCmd.PersistentFlags().StringVar(&gitObject, "git-object", "", "Verify this blob of the current git repository, by object id (or anything git resolves to a blob) instead of a file, the only argument is then the signature path")
+	verifyCmd.PersistentFlags().StringVar(&gitFormat, "format", "patch", "What was signed of --git-range: patch (with the commit messages) or diff")
+	verifyCmd.PersistentFlags().BoolVar(&manifest, "manifest", false, "Treat the file as a manifest written by \"ssign manifest\", and check the listed files after verifying its signature")
+	verifyCmd.PersistentFlags().StringVar(&sortOrder, "sort-order", "byte", "Order the --manifest entries must be sorted in: byte or unicode")
+	verifyCmd.PersistentFlags().BoolVar(&exactManifest, "exact", false, "Also fail --manifest when the directory has files that are not listed in it")
+	verifyCmd.PersistentFlags().BoolVar(&manifestSignatureOnly, "manifest-signature-only", false, "Only verify the signature of the manifest, without checking the listed files")
+	verifyCmd.PersistentFlags().StringVar(&dnsIdentity, "dns-identity", "", "Require the key fingerprint to be published as a \""+dnsFingerprintPrefix+"SHA256:...\" TXT record of this name (uses the embedded key if --public-key is not set)")
+	verifyCmd.PersistentFlags().IntVar(&readAheadFiles, "read-ahead", 0, "When verifying a directory, read up to this many files in the background while verifying the current one, so I/O and verification overlap (each file read ahead is kept in memory)")
+	verifyCmd.PersistentFlags().Var(&readBufferSize, "read-buffer-size", "Size of the chunks used to read the file, between 4KiB and 64MiB")
+	verifyCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "Print the result, or the error and its code, as JSON")
+	verifyCmd.PersistentFlags().BoolVar(&redactJSON, "redact-key-paths-in-json", false, "With --json, replace the file and signature paths by their SHA256, and the key path by the key fingerprint, including where they appear in the warning and error, to publish results without internal paths")
+	verifyCmd.PersistentFlags().BoolVar(&lenientUnsigned, "lenient-unsigned", false, "Exit with status 0, with a warning, when the file has no signature at all, e.g. while migrating to mandatory signing; a signature that is present but invalid still exits with status 1")
+	verifyCmd.PersistentFlags().BoolVar(&pauseOnError, "pause-on-error", false, "When verification fails on a terminal, ask whether to show the explanation, retry with another public key, or continue")
+	verifyCmd.PersistentFlags().BoolVar(&compatKeygen, "compat-keygen-verify", false, "When verification fails, also verify with \"ssh-keygen -Y verify\", and report whether it agrees, to diagnose incompatibilities with OpenSSH")
+	verifyCmd.PersistentFlags().BoolVar(&explain, "explain", false, "When verification fails, print each check and which one failed")
+	verifyCmd.PersistentFlags().StringVar(&output, "output", "", "Report format when verifying a directory: junit")
+	verifyCmd.PersistentFlags().BoolVar(&groupKeys, "group-by-key", false, "When verifying a directory, report how many files each key signed, and how many of them failed")
+	verifyCmd.PersistentFlags().StringVar(&metricsFile, "metrics", "", "Write counters of the directory verification to this file, in the Prometheus text format")
+	verifyCmd.PersistentFlags().StringVar(&reportFile, "report-file", "", "Write the directory verification report to this file instead of stdout")
+	verifyCmd.PersistentFlags().StringVar(&outputTemplate, "template", "", "Go template used to print the result instead of the default output (fields: "+templateFields+")")
+	markFlagConflicts(verifyCmd)
+
+	roundtripCmd := &cobra.Command{
+		Use:   "roundtrip",
+		Short: "Sign and verify a file, without keeping the signature",
+		Long: `Signs the given file into a temporary location, verifies it with the given
+public key, and cleans up.
+
+Useful as a smoke test in CI to assert the key pair and the environment work.`,
+		Example: `ssign roundtrip --key id_ed25519 --public-key id_ed25519.pub README.md`,
+		Args:    cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			key, err := openPrivateKey(keyPath)
+			message, err := os.ReadFile(args[0])
+			if err != nil {
+				return fmt.Errorf("could open file %s: %w", args[0], err)
+			}
+
+			p := newPrompter(cmd)
+			p.PassphraseCommand = passphraseCommand
+			key, err := openPrivateKey(keyPath, keySelector{Index: -1}, p, ignorePermissions)
+			if err != nil {
+				return fmt.Errorf("roundtrip failed to open key %s: %w", keyPath, err)
+			}
+
+			data, err := signMessage(key, message, namespace)
+			if err != nil {
+				return fmt.Errorf("roundtrip failed to sign: %w", err)
+			}
+
+			tmp, err := os.CreateTemp("", "ssign-*.ssig")
+			if err != nil {
+				return fmt.Errorf("roundtrip failed to create temporary signature: %w", err)
+			}
+			defer os.Remove(tmp.Name())
+			if _, err := tmp.Write(data); err != nil {
+				_ = tmp.Close()
+				return fmt.Errorf("roundtrip failed to write signature: %w", err)
+			}
+			if err := tmp.Close(); err != nil {
+				return fmt.Errorf("roundtrip failed to write signature: %w", err)
+			}
+
+			pub, err := openPublicKey(pubkeyPath)
+			if err != nil {
+				return fmt.Errorf("roundtrip failed to parse public key %s: %w", pubkeyPath, err)
+			}
+
+			signature, err := os.ReadFile(tmp.Name())
+			if err != nil {
+				return fmt.Errorf("roundtrip failed to read signature back: %w", err)
+			}
+
+			if err := verifyMessage(pub, message, signature, namespace); err != nil {
+				return fmt.Errorf("roundtrip failed to verify: %w", err)
+			}
+
+			styles := mustStyles()
+			cmd.Println(styles.Header.String())
+			cmd.Println(styles.Text.Render(
+				"Signed and verified " +
+					styles.Code.Render(args[0]) +
+					" with " +
+					styles.Code.Render(keyPath) +
+					" and " +
+					styles.Code.Render(pubkeyPath) +
+					".",
+			))
+			return nil
+		},
+	}
+	roundtripCmd.PersistentFlags().StringVar(&keyPath, "key", os.ExpandEnv("$HOME/.ssh/id_ed25519"), "SSH Key to be used")
+	roundtripCmd.PersistentFlags().BoolVar(&ignorePermissions, "ignore-permissions", false, "Use the key even if its file is accessible by the group or others")
+	roundtripCmd.PersistentFlags().StringVar(&passphraseCommand, "passphrase-command", "", "Run this command, e.g. \"pass show ssh/key\", and use its output as the key passphrase instead of asking for it")
+	roundtripCmd.PersistentFlags().StringVar(&pubkeyPath, "public-key", os.ExpandEnv("$HOME/.ssh/id_ed25519.pub"), "SSH public key to be used")
+	roundtripCmd.PersistentFlags().StringVar(&namespace, "namespace", defaultNamespace, "Namespace of the signature")
+
+	var summary bool
+	var inspectFormat string
+	inspectCmd := &cobra.Command{
+		Use:   "inspect",
+		Short: "Show the details of a signature",
+		Long: `Shows the details embedded in a signature, without verifying it.
+
+SSH signatures do not carry a signing time, so there's no timestamp to report:
+the summary says who signed it, not when.`,
+		Example: `ssign inspect README.md.ssig
+ssign inspect --summary README.md.ssig
+ssign inspect --format yaml README.md.ssig`,
+		Aliases: []string{"i"},
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			in, err := os.ReadFile(args[0])
+			if err != nil {
+				return fmt.Errorf("could not open signature: %w", err)
+			}
+
+			sig, err := parseSignature(in)
+			if err != nil {
+				return fmt.Errorf("could not parse signature %s: %w", args[0], err)
+			}
+
+			info := newSignatureInfo(args[0], sig)
+			if summary {
+				if inspectFormat != "text" {
+					return fmt.Errorf("--summary cannot be used with --format %s", inspectFormat)
+				}
+				cmd.Printf(
+					"%s: signed by %s (%s) under namespace %s\n",
+					args[0],
+					info.KeyFingerprint,
+					info.KeyType,
+					info.Namespace,
+				)
+				return nil
+			}
+
+			switch inspectFormat {
+			case "json":
+				return printJSON(cmd.OutOrStdout(), info)
+			case "yaml":
+				enc := yaml.NewEncoder(cmd.OutOrStdout())
+				if err := enc.Encode(info); err != nil {
+					return err
+				}
+				return enc.Close()
+			case "text":
+				styles := mustStyles()
+				cmd.Println(styles.Header.String())
+				for _, line := range info.lines() {
+					cmd.Println(styles.Text.Render(line[0] + ": " + styles.Code.Render(line[1])))
+				}
+				return nil
+			default:
+				return fmt.Errorf("invalid format %q, expected text, json, or yaml", inspectFormat)
+			}
+		},
+	}
+	inspectCmd.PersistentFlags().BoolVar(&summary, "summary", false, "Print a one-line summary of who signed it")
+	inspectCmd.PersistentFlags().StringVar(&inspectFormat, "format", "text", "Output format: text, json, or yaml")
+
+	var compareFormat string
+	compareCmd := &cobra.Command{
+		Use:   "compare",
+		Short: "Compare two signatures",
+		Long: `Compares two signatures, without verifying them: whether they were made by
+the same key, with the same namespace and algorithms, and over the same
+content.
+
+Signatures only hold a hash of the content they cover, signed, so the
+content can only be compared when both were made by the same key, with the
+same namespace and algorithms, using a deterministic algorithm (Ed25519 or
+RSA), the signatures being identical then if and only if the content is the
+same. Otherwise, it's reported as unknown.`,
+		Example: `ssign compare README.md.ssig README.md.old.ssig
+ssign compare --format json a.ssig b.ssig`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var sigs [2]*signature
+			for i, name := range args {
+				in, err := os.ReadFile(name)
+				if err != nil {
+					return fmt.Errorf("could not open signature: %w", err)
+				}
+				sigs[i], err = parseSignature(in)
+				if err != nil {
+					return fmt.Errorf("could not parse signature %s: %w", name, err)
+				}
+			}
+
+			c := compareSignatures(sigs[0], sigs[1], args[0], args[1])
+			switch compareFormat {
+			case "json":
+				return printJSON(cmd.OutOrStdout(), c)
+			case "text":
+				styles := mustStyles()
+				cmd.Println(styles.Header.String())
+				verdict := "The signatures are equivalent: same key, namespace, algorithms, and content."
+				if !c.Equivalent {
+					verdict = "The signatures are not known to be equivalent."
+				}
+				cmd.Println(styles.Text.Render(verdict))
+				for _, line := range c.lines() {
+					cmd.Println(styles.Text.Render(line[0] + ": " + styles.Code.Render(line[1])))
+				}
+				return nil
+			default:
+				return fmt.Errorf("invalid format %q, expected text or json", compareFormat)
+			}
+		},
+	}
+	compareCmd.PersistentFlags().StringVar(&compareFormat, "format", "text", "Output format: text or json")
+
+	var keyOutput string
+	extractKeyCmd := &cobra.Command{
+		Use:   "extract-key",
+		Short: "Extract the public key embedded in a signature",
+		Long: `Extracts the public key embedded in a signature, in the authorized_keys format.
+
+Anyone can create a signature embedding any key they own: extracting the key
+does not establish trust in it.`,
+		Example: `ssign extract-key README.md.ssig
+ssign extract-key README.md.ssig -o signer.pub`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			in, err := os.ReadFile(args[0])
+			if err != nil {
+				return fmt.Errorf("could not open signature: %w", err)
+			}
+
+			sig, err := parseSignature(in)
+			if err != nil {
+				return fmt.Errorf("could not parse signature %s: %w", args[0], err)
+			}
+
+			key := ssh.MarshalAuthorizedKey(sig.PublicKey)
+			cmd.PrintErrln("Warning: the extracted key is not trusted, verify its fingerprint through another channel before relying on it.")
+			if keyOutput == "" {
+				_, err := cmd.OutOrStdout().Write(key)
+				return err
+			}
+
+			if err := os.WriteFile(keyOutput, key, 0o644); err != nil {
+				return fmt.Errorf("could not write public key %s: %w", keyOutput, err)
+			}
+
+			styles := mustStyles()
+			cmd.Println(styles.Header.String())
+			cmd.Println(styles.Text.Render(
+				"Extracted key " +
+					styles.Code.Render(ssh.FingerprintSHA256(sig.PublicKey)) +
+					" to " +
+					styles.Code.Render(keyOutput) +
+					".",
+			))
+			return nil
+		},
+	}
+	extractKeyCmd.PersistentFlags().StringVarP(&keyOutput, "output", "o", "", "Where to write the public key (defaults to stdout)")
+
+	whoSignedCmd := &cobra.Command{
+		Use:   "whosigned",
+		Short: "Show who claims to have signed a file",
+		Long: `Shows the key embedded in a signature, and the identity of its certificate, if
+it's an SSH certificate, after checking the signature matches the file under
+that key.
+
+No trusted key is needed, and none is asserted: anyone can sign a file with
+any key they own, so this is who the signature claims made it, not a verified
+fact. Use "ssign verify" with a trusted key for that.`,
+		Example: `ssign whosigned README.md
+ssign whosigned README.md README.sig`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			message, err := readFile(args[0], readBufferSize)
+			if err != nil {
+				return fmt.Errorf("could open file %s: %w", args[0], err)
+			}
+			var sigName string
+			if len(args) > 1 {
+				sigName = args[1]
+			}
+			sigName, signature, err := readSignature(args[0], sigName, false)
+			if err != nil {
+				return err
+			}
+
+			sig, err := parseSignature(signature)
+			if err != nil {
+				return fmt.Errorf("could not parse signature %s: %w", sigName, err)
+			}
+			if recorded := attestationHeaders(signature); len(recorded) > 0 {
+				message = attestedMessage(message, recorded)
+			}
+			if err := verifyMessage(sig.PublicKey, message, signature, sig.Namespace); err != nil {
+				return fmt.Errorf("signature %s does not match %s under its own key: %w", sigName, args[0], err)
+			}
+
+			lines := [][2]string{
+				{"Claimed signer", ssh.FingerprintSHA256(sig.PublicKey)},
+				{"Key type", sig.PublicKey.Type()},
+				{"Namespace", sig.Namespace},
+			}
+			if cert, ok := sig.PublicKey.(*ssh.Certificate); ok {
+				lines = append(lines,
+					[2]string{"Certificate key ID", cert.KeyId},
+					[2]string{"Certificate principals", strings.Join(cert.ValidPrincipals, ", ")},
+					[2]string{"Certificate CA", ssh.FingerprintSHA256(cert.SignatureKey)},
+				)
+			}
+
+			styles := mustStyles()
+			cmd.Println(styles.Header.String())
+			for _, line := range lines {
+				cmd.Println(styles.Text.Render(line[0] + ": " + styles.Code.Render(line[1])))
+			}
+			cmd.PrintErrln("Warning: this is who the signature claims made it, the key is not trusted: use \"ssign verify\" with a trusted key to check it.")
+			return nil
+		},
+	}
+
+	var bundleFormat, bundleOutput string
+	bundleCmd := &cobra.Command{
+		Use:   "bundle",
+		Short: "Bundle a file with its signature and public key to verify it offline",
+		Long: `Writes the file, its signature, and the public key that made it to a single
+archive, so it can be verified somewhere else with "ssign verify --bundle".
+
+The archive is a tar.gz or a zip file, as given by --bundle-format, and keeps
+the mode of the file. The signature must verify under the public key.`,
+		Example: `ssign bundle README.md
+ssign bundle --bundle-format zip -o README.zip README.md README.sig`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ext := map[string]string{"tar": ".bundle.tar.gz", "zip": ".bundle.zip"}[bundleFormat]
+			if ext == "" {
+				return fmt.Errorf("invalid bundle format %q, expected tar or zip", bundleFormat)
+			}
+			info, err := os.Stat(args[0])
+			if err != nil {
+				return fmt.Errorf("could not open file %s: %w", args[0], err)
+			}
+			if !info.Mode().IsRegular() {
+				return fmt.Errorf("could not bundle %s: not a regular file", args[0])
+			}
+			message, err := readFile(args[0], readBufferSize)
+			if err != nil {
+				return fmt.Errorf("could not open file %s: %w", args[0], err)
+			}
+			var sigName string
+			if len(args) > 1 {
+				sigName = args[1]
+			}
+			sigName, signature, err := readSignature(args[0], sigName, false)
 			if err != nil {
-				return fmt.Errorf("key %s: %w", keyPath, err)
+				return err
 			}
-
-			signer, ok := key.(ssh.AlgorithmSigner)
-			if !ok {
-				return fmt.Errorf("cannot use this key")
+			sig, err := parseSignature(signature)
+			if err != nil {
+				return fmt.Errorf("could not parse signature %s: %w", sigName, err)
 			}
 
-			message, err := os.ReadFile(args[0])
+			pubs, err := openPublicKeys(pubkeyPath)
 			if err != nil {
-				return fmt.Errorf("could open file %s: %w", args[0], err)
+				return fmt.Errorf("could not parse public key %s: %w", pubkeyPath, err)
+			}
+			signed := message
+			if recorded := attestationHeaders(signature); len(recorded) > 0 {
+				signed = attestedMessage(message, recorded)
+			}
+			var pub ssh.PublicKey
+			for _, p := range pubs {
+				if ssh.FingerprintSHA256(p) == ssh.FingerprintSHA256(sig.PublicKey) && verifyMessage(p, signed, signature, sig.Namespace) == nil {
+					pub = p
+					break
+				}
+			}
+			if pub == nil {
+				return fmt.Errorf("could not bundle %s: signature %s was not made by %s", args[0], sigName, pubkeyPath)
 			}
 
-			data, err := sshsig.Sign(signer, rand.Reader, message, namespace)
+			if bundleOutput == "" {
+				bundleOutput = args[0] + ext
+			}
+			f, err := os.Create(bundleOutput)
+			if err != nil {
+				return fmt.Errorf("could not create bundle %s: %w", bundleOutput, err)
+			}
+			err = writeBundle(f, bundle{
+				Name:      filepath.Base(args[0]),
+				Mode:      info.Mode(),
+				ModTime:   info.ModTime(),
+				Content:   message,
+				Signature: signature,
+				PublicKey: ssh.MarshalAuthorizedKey(pub),
+			}, bundleFormat)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
 			if err != nil {
-				return fmt.Errorf("could not sign: %w", err)
+				_ = os.Remove(bundleOutput)
+				return fmt.Errorf("could not write bundle %s: %w", bundleOutput, err)
 			}
 
-			var sigName string
-			if len(args) > 1 {
-				sigName = args[1]
-			} else {
-				sigName = args[0] + ".ssig"
+			styles := mustStyles()
+			cmd.Println(styles.Header.String())
+			cmd.Println(styles.Text.Render(
+				"Bundled " +
+					styles.Code.Render(args[0]) +
+					" with its signature and public key at " +
+					styles.Code.Render(bundleOutput) +
+					".",
+			))
+			return nil
+		},
+	}
+	bundleCmd.PersistentFlags().StringVar(&pubkeyPath, "public-key", os.ExpandEnv("$HOME/.ssh/id_ed25519.pub"), "SSH public key that made the signature, included in the bundle")
+	bundleCmd.PersistentFlags().StringVar(&bundleFormat, "bundle-format", "tar", "Format of the bundle: tar (a tar.gz archive) or zip")
+	bundleCmd.PersistentFlags().StringVarP(&bundleOutput, "output", "o", "", "Path of the bundle (default is the file with a .bundle.tar.gz or .bundle.zip extension)")
+
+	var keygenOpts keygenOptions
+	var keygenForce bool
+	keygenCmd := &cobra.Command{
+		Use:   "keygen",
+		Short: "Generate an SSH key pair",
+		Long: `Generates an SSH key pair, like ssh-keygen: the private key in the OpenSSH
+format, and the public key next to it, with a .pub extension.
+
+Without flags, on a terminal, it asks for the key type, size, path, comment,
+and whether to encrypt it, and confirms before overwriting an existing key.`,
+		Example: `ssign keygen
+ssign keygen --type ed25519 --file ./id_ed25519 --comment ci@example.com
+ssign keygen --type rsa --bits 4096 --encrypt --passphrase-command 'pass show ssh/new'`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			p := newPrompter(cmd)
+			p.PassphraseCommand = passphraseCommand
+			o := keygenOpts
+			force := keygenForce
+			var passphrase []byte
+			var flags bool
+			cmd.LocalFlags().VisitAll(func(f *pflag.Flag) { flags = flags || f.Changed })
+			if !flags && p.interactive() {
+				a, err := askKeygenOptions(p)
+				if err != nil {
+					return err
+				}
+				if (exists(a.Path) || exists(a.Path+".pub")) && !a.Overwrite {
+					return fmt.Errorf("%w: %s, not overwritten", errKeyExists, a.Path)
+				}
+				o, passphrase, force = a.keygenOptions, a.Passphrase, a.Overwrite
+			}
+			if o.Path == "" {
+				o.Path = defaultKeyPath(o.Type)
+			}
+			if err := o.check(); err != nil {
+				return err
+			}
+			if passphraseCommand != "" && !o.Encrypt {
+				return fmt.Errorf("--passphrase-command requires --encrypt")
+			}
+
+			if o.Encrypt && passphrase == nil {
+				if passphraseCommand == "" && !p.interactive() {
+					return fmt.Errorf("--encrypt needs a terminal to ask for the passphrase, or --passphrase-command")
+				}
+				var err error
+				passphrase, err = askNewPassphrase(p)
+				if err != nil {
+					return err
+				}
 			}
 
-			if err := os.WriteFile(sigName, data, 0o644); err != nil {
-				return fmt.Errorf("could not write signature %s: %w", sigName, err)
+			pub, err := writeKeyPair(o, passphrase, force)
+			if err != nil {
+				return err
 			}
 
 			styles := mustStyles()
 			cmd.Println(styles.Header.String())
 			cmd.Println(styles.Text.Render(
-				"Signed " +
-					styles.Code.Render(args[0]) +
-					" with " +
-					styles.Code.Render(keyPath) +
+				"Generated key " +
+					styles.Code.Render(ssh.FingerprintSHA256(pub)) +
+					" at " +
+					styles.Code.Render(o.Path) +
 					".",
 			))
 			cmd.Println(styles.Text.Render(
-				"Signature stored at " +
-					styles.Code.Render(sigName) +
+				"Public key stored at " +
+					styles.Code.Render(o.Path+".pub") +
 					".",
 			))
 			return nil
 		},
 	}
-	signCmd.PersistentFlags().StringVar(&keyPath, "key", os.ExpandEnv("$HOME/.ssh/id_ed25519"), "SSH Key to be used")
+	keygenCmd.PersistentFlags().StringVar(&keygenOpts.Type, "type", "ed25519", "Key type: ed25519, ecdsa, or rsa")
+	keygenCmd.PersistentFlags().IntVar(&keygenOpts.Bits, "bits", 0, "Key size: 256, 384, or 521 for ecdsa (default 256), 2048, 3072, or 4096 for rsa (default 3072)")
+	keygenCmd.PersistentFlags().StringVarP(&keygenOpts.Path, "file", "f", "", "Where to store the private key, the public key going to the same path plus .pub (defaults to ~/.ssh/id_<type>)")
+	keygenCmd.PersistentFlags().StringVar(&keygenOpts.Comment, "comment", defaultKeyComment(), "Comment of the key")
+	keygenCmd.PersistentFlags().BoolVar(&keygenOpts.Encrypt, "encrypt", false, "Encrypt the private key with a passphrase, asked for twice on a terminal")
+	keygenCmd.PersistentFlags().StringVar(&passphraseCommand, "passphrase-command", "", "Run this command, e.g. \"pass show ssh/key\", and use its output as the passphrase of --encrypt instead of asking for it")
+	keygenCmd.PersistentFlags().BoolVar(&keygenForce, "force", false, "Overwrite the key files if they already exist")
 
-	var pubkeyPath string
-	verifyCmd := &cobra.Command{
-		Use:   "verify [signature]",
-		Short: "Verify a signature",
-		Example: `ssign verify README.md
-ssign verify --public-key id_ed25519.pub README.md README.md.ssig`,
-		Aliases: []string{"v"},
-		Args:    cobra.RangeArgs(1, 2),
+	var convertIn, convertFormat, convertOutput string
+	convertPubkeyCmd := &cobra.Command{
+		Use:   "convert-pubkey",
+		Short: "Convert a public key between the authorized_keys and wire formats",
+		Example: `ssign convert-pubkey --in id_ed25519.pub --format wire -o id_ed25519.bin
+ssign convert-pubkey --in id_ed25519.bin --format authorized_keys`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			pub, err := openPublicKey(convertIn)
+			if err != nil {
+				return fmt.Errorf("could not parse public key %s: %w", convertIn, err)
+			}
+
+			key, err := encodePublicKey(pub, convertFormat)
+			if err != nil {
+				return err
+			}
+			if convertOutput == "" {
+				_, err := cmd.OutOrStdout().Write(key)
+				return err
+			}
+
+			if err := os.WriteFile(convertOutput, key, 0o644); err != nil {
+				return fmt.Errorf("could not write public key %s: %w", convertOutput, err)
+			}
+
+			styles := mustStyles()
+			cmd.Println(styles.Header.String())
+			cmd.Println(styles.Text.Render(
+				"Converted key " +
+					styles.Code.Render(ssh.FingerprintSHA256(pub)) +
+					" to " +
+					styles.Code.Render(convertOutput) +
+					".",
+			))
+			return nil
+		},
+	}
+	convertPubkeyCmd.PersistentFlags().StringVar(&convertIn, "in", "", "Public key to convert, in either format")
+	convertPubkeyCmd.PersistentFlags().StringVar(&convertFormat, "format", "authorized_keys", "Format to convert to: authorized_keys or wire")
+	convertPubkeyCmd.PersistentFlags().StringVarP(&convertOutput, "output", "o", "", "Where to write the public key (defaults to stdout)")
+	_ = convertPubkeyCmd.MarkPersistentFlagRequired("in")
+
+	var requireSigned []string
+	scanCmd := &cobra.Command{
+		Use:   "scan",
+		Short: "Check that all matching files in a directory are signed",
+		Long: `Walks the given directory, and fails if any file matching --require-signed
+does not have a valid signature next to it, with a .ssig extension.
+
+Patterns use the syntax of "path.Match", and are matched against the file
+name, or against the path relative to the directory if they contain a slash.`,
+		Example: `ssign scan dist/ --require-signed '*.tar.gz'
+ssign scan dist/ --require-signed '*.tar.gz' --require-signed 'bin/*' --json`,
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			pub, err := openPublicKey(pubkeyPath)
+			if len(requireSigned) == 0 {
+				return fmt.Errorf("--require-signed is required")
+			}
+			pubs, err := openPublicKeys(pubkeyPath)
 			if err != nil {
-				return fmt.Errorf("could not parse public key %s: %w", pubkeyPath, err)
+				return fmt.Errorf("could not parse public key %s: %w", pubkeyPath, withCode(codeKey, err))
+			}
+			v := verifier{
+				Pubs:      pubs,
+				KeyName:   pubkeyPath,
+				Namespace: namespace,
+				Policy:    policy{Namespace: namespace},
 			}
 
-			message, err := os.ReadFile(args[0])
+			files, err := findRequired(args[0], requireSigned)
 			if err != nil {
-				return fmt.Errorf("could not open subject: %w", err)
+				return fmt.Errorf("could not list files in %s: %w", args[0], err)
+			}
+			results := v.scan(files, readBufferSize)
+			failures := batchFailures(results)
+			// unsigned files are still reported, but don't fail the scan.
+			allowed := 0
+			if lenientUnsigned {
+				for _, r := range results {
+					if errors.Is(r.Err, errNotSigned) {
+						allowed++
+					}
+				}
 			}
 
-			var sigName string
-			if len(args) > 1 {
-				sigName = args[1]
+			if jsonOutput {
+				out := make([]jsonResult, 0, len(results))
+				for _, r := range results {
+					jr := jsonResult{result: r.result, OK: r.Err == nil}
+					switch {
+					case lenientUnsigned && errors.Is(r.Err, errNotSigned):
+						jr.OK, jr.Warning = true, r.Err.Error()
+					case r.Err != nil:
+						jr.Error, jr.ErrorCode = r.Err.Error(), codeOf(r.Err)
+					}
+					if redactJSON {
+						jr = jr.redacted()
+					}
+					out = append(out, jr)
+				}
+				if err := printJSON(cmd.OutOrStdout(), out); err != nil {
+					return fmt.Errorf("could not write report: %w", err)
+				}
 			} else {
-				sigName = args[0] + ".ssig"
+				styles := mustStyles()
+				cmd.Println(styles.Header.String())
+				for _, r := range results {
+					switch {
+					case errors.Is(r.Err, errNotSigned):
+						cmd.Println(styles.Text.Render("Unsigned " + styles.Code.Render(r.File) + "."))
+					case r.Err != nil:
+						cmd.Println(styles.Text.Render(
+							"Invalid signature for " +
+								styles.Code.Render(r.File) +
+								": " +
+								r.Err.Error(),
+						))
+					}
+				}
+				cmd.Println(styles.Text.Render(
+					"Verified " +
+						styles.Code.Render(fmt.Sprintf("%d", len(results)-failures)) +
+						" of " +
+						styles.Code.Render(fmt.Sprintf("%d", len(results))) +
+						" matching files in " +
+						styles.Code.Render(args[0]) +
+						".",
+				))
+			}
+
+			if failures > allowed {
+				return fmt.Errorf("%w: %d of %d matching files are unsigned or invalid", errBatchFailed, failures-allowed, len(results))
+			}
+			return nil
+		},
+	}
+	scanCmd.PersistentFlags().StringArrayVar(&requireSigned, "require-signed", nil, "Pattern of the files which must be signed, e.g. '*.tar.gz', can be repeated")
+	scanCmd.PersistentFlags().StringVar(&pubkeyPath, "public-key", os.ExpandEnv("$HOME/.ssh/id_ed25519.pub"), "SSH public key to be used")
+	scanCmd.PersistentFlags().StringVar(&namespace, "namespace", defaultNamespace, "Namespace of the signature")
+	scanCmd.PersistentFlags().Var(&readBufferSize, "read-buffer-size", "Size of the chunks used to read the files, between 4KiB and 64MiB")
+	scanCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "Print the result of each matching file, with the error and its code, as JSON")
+	scanCmd.PersistentFlags().BoolVar(&redactJSON, "redact-key-paths-in-json", false, "With --json, replace the file and signature paths by their SHA256, and the key path by the key fingerprint, including where they appear in the warning and error, to publish results without internal paths")
+	scanCmd.PersistentFlags().BoolVar(&lenientUnsigned, "lenient-unsigned", false, "Still list the unsigned files, but only exit with status 1 when a signature is present and invalid, e.g. while migrating to mandatory signing")
+
+	var migrateFrom, migrateTo, migrateFormat string
+	var dryRun bool
+	migrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Rename and re-encode signatures in bulk",
+		Long: `Renames all signatures under the given directory from one extension to another,
+re-encoding them as PEM or as a single base64 line.
+
+Each migrated signature is checked to decode to the exact same signature as
+the original before anything is written, and is written atomically before the
+original is removed.`,
+		Example: `ssign migrate dist/ --from .ssig --to .sig
+ssign migrate dist/ --from .ssig --to .ssig --format base64 --dry-run`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if migrateFrom == "" || migrateTo == "" {
+				return fmt.Errorf("--from and --to cannot be empty")
 			}
-			signature, err := os.ReadFile(sigName)
+
+			migrations, err := findMigrations(args[0], migrateFrom, migrateTo)
 			if err != nil {
-				return fmt.Errorf("could not open signature: %w", err)
+				return fmt.Errorf("could not list signatures in %s: %w", args[0], err)
+			}
+
+			styles := mustStyles()
+			if dryRun {
+				cmd.Println(styles.Header.String())
+				for _, m := range migrations {
+					cmd.Println(styles.Text.Render(
+						"Would migrate " +
+							styles.Code.Render(m.From) +
+							" to " +
+							styles.Code.Render(m.To) +
+							".",
+					))
+				}
+				return nil
+			}
+
+			for _, m := range migrations {
+				if err := m.prepare(migrateFormat); err != nil {
+					return fmt.Errorf("could not migrate %s: %w", m.From, err)
+				}
+			}
+			for _, m := range migrations {
+				if err := m.write(); err != nil {
+					return fmt.Errorf("could not migrate %s: %w", m.From, err)
+				}
 			}
 
-			block, _ := pem.Decode(signature)
+			cmd.Println(styles.Header.String())
+			cmd.Println(styles.Text.Render(
+				"Migrated " +
+					styles.Code.Render(fmt.Sprintf("%d", len(migrations))) +
+					" signatures in " +
+					styles.Code.Render(args[0]) +
+					".",
+			))
+			return nil
+		},
+	}
+	migrateCmd.PersistentFlags().StringVar(&migrateFrom, "from", ".ssig", "Current extension of the signatures")
+	migrateCmd.PersistentFlags().StringVar(&migrateTo, "to", ".ssig", "New extension of the signatures")
+	migrateCmd.PersistentFlags().StringVar(&migrateFormat, "format", "pem", "Encoding of the migrated signatures: pem or base64")
+	migrateCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Only print what would be migrated")
 
-			if err := sshsig.Verify(pub, message, block.Bytes, namespace); err != nil {
-				return fmt.Errorf("could not verify: %w", err)
+	pruneCmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete the signatures of files that don't exist anymore",
+		Long: `Deletes the ".ssig" signatures under the given directory whose file was
+removed, e.g. "README.md.ssig" without a "README.md" next to it.
+
+Signatures named by content hash, as read by "ssign verify --by-content-hash-name",
+are kept.`,
+		Example: `ssign prune dist/ --dry-run
+ssign prune dist/`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			orphans, err := findOrphans(args[0])
+			if err != nil {
+				return fmt.Errorf("could not list signatures in %s: %w", args[0], err)
 			}
 
 			styles := mustStyles()
 			cmd.Println(styles.Header.String())
+			verb := "Pruned "
+			if dryRun {
+				verb = "Would prune "
+			}
+			for _, name := range orphans {
+				if dryRun {
+					cmd.Println(styles.Text.Render(
+						"Would prune " +
+							styles.Code.Render(name) +
+							".",
+					))
+					continue
+				}
+				if err := os.Remove(name); err != nil {
+					return fmt.Errorf("could not prune %s: %w", name, err)
+				}
+			}
 			cmd.Println(styles.Text.Render(
-				"Valid signature for " +
+				verb +
+					styles.Code.Render(fmt.Sprintf("%d", len(orphans))) +
+					" orphaned signatures in " +
 					styles.Code.Render(args[0]) +
-					" at " +
-					styles.Code.Render(sigName) +
 					".",
 			))
+			return nil
+		},
+	}
+	pruneCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Only print what would be pruned")
+
+	var certOutput string
+	certInfoCmd := &cobra.Command{
+		Use:   "cert-info",
+		Short: "Show the details of an SSH certificate",
+		Example: `ssign cert-info id_ed25519-cert.pub
+ssign cert-info --output json id_ed25519-cert.pub`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cert, err := openCertificate(args[0])
+			if err != nil {
+				return fmt.Errorf("could not parse certificate %s: %w", args[0], err)
+			}
+
+			info := newCertInfo(cert)
+			switch certOutput {
+			case "json":
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(info)
+			case "text":
+				styles := mustStyles()
+				cmd.Println(styles.Header.String())
+				for _, line := range info.lines() {
+					cmd.Println(styles.Text.Render(line[0] + ": " + styles.Code.Render(line[1])))
+				}
+				return nil
+			default:
+				return fmt.Errorf("invalid output %q, expected text or json", certOutput)
+			}
+		},
+	}
+	certInfoCmd.PersistentFlags().StringVar(&certOutput, "output", "text", "Output format: text or json")
+
+	manifestCmd := &cobra.Command{
+		Use:   "manifest",
+		Short: "Write a manifest with the SHA256 of all files in a directory",
+		Long: `Write a manifest with the SHA256 of all files in a directory, to ` + manifestName + ` in it.
+
+Sign the manifest with "ssign sign", and check it, together with all the listed
+files, with "ssign verify --manifest".
+
+The entries are sorted, so the same files always give the same manifest, and
+the same signature can be reproduced. Use --sort-order to match the order
+expected by other tools reading the manifest, and pass the same --sort-order
+to verify.`,
+		Example: `ssign manifest dist/
+ssign sign dist/` + manifestName + `
+ssign verify --manifest dist/` + manifestName,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := buildManifest(args[0], sortOrder)
+			if err != nil {
+				return fmt.Errorf("could not list files in %s: %w", args[0], err)
+			}
+
+			name := filepath.Join(args[0], manifestName)
+			if err := os.WriteFile(name, encodeManifest(entries), 0o644); err != nil {
+				return fmt.Errorf("could not write manifest %s: %w", name, err)
+			}
+
+			styles := mustStyles()
+			cmd.Println(styles.Header.String())
 			cmd.Println(styles.Text.Render(
-				"Verified signed for key " +
-					styles.Code.Render(pubkeyPath) +
+				"Listed " +
+					styles.Code.Render(fmt.Sprintf("%d", len(entries))) +
+					" files in " +
+					styles.Code.Render(name) +
 					".",
 			))
 			return nil
 		},
 	}
-	verifyCmd.PersistentFlags().StringVar(&pubkeyPath, "public-key", os.ExpandEnv("$HOME/.ssh/id_ed25519.pub"), "SSH public key to be used")
 
-	cmd.AddCommand(signCmd, verifyCmd)
+	manifestCmd.PersistentFlags().StringVar(&sortOrder, "sort-order", "byte", "Order of the entries: byte (like sha256sum and \"LC_ALL=C sort\") or unicode (Unicode collation)")
+
+	cmd.AddCommand(signCmd, verifyCmd, roundtripCmd, inspectCmd, compareCmd, extractKeyCmd, whoSignedCmd, bundleCmd, scanCmd, migrateCmd, pruneCmd, certInfoCmd, manifestCmd, convertPubkeyCmd, keygenCmd)
+
+	cmd.PersistentFlags().Bool("no-fang", false, "Run without styled output and error handling (same as SSIGN_NO_FANG=1)")
+	cmd.PersistentFlags().BoolVar(&showRawError, "show-raw-error", false, "Add the chain of underlying errors, with their types, to the error message, e.g. to see what the ssh and sshsig libraries returned (--json errors are not changed)")
+	showRawErrors(cmd, &showRawError)
+
+	if noFang(os.Args[1:]) {
+		cmd.SetOut(&colorprofile.Writer{Forward: os.Stdout, Profile: colorprofile.NoTTY})
+		cmd.SetErr(&colorprofile.Writer{Forward: os.Stderr, Profile: colorprofile.NoTTY})
+		// errors are printed here, rather than by cobra, to leave out the
+		// silenced ones.
+		cmd.SilenceErrors = true
+		cmd.SilenceUsage = true
+		if c, err := cmd.ExecuteC(); err != nil {
+			if !isSilenced(err) {
+				c.PrintErrln(c.ErrPrefix(), err.Error())
+				c.Println(c.UsageString())
+			}
+			os.Exit(1)
+		}
+		return
+	}
 
-	if err := fang.Execute(context.Background(), cmd); err != nil {
+	if err := fang.Execute(context.Background(), cmd, fang.WithErrorHandler(fangErrorHandler)); err != nil {
 		os.Exit(1)
 	}
 }
 
+// verifyArgs validates the arguments of the verify command, which depend on
+// its flags.
+func verifyArgs(cmd *cobra.Command, args []string) error {
+	if cmd.Flags().Changed("receipt") && len(args) > 0 {
+		return fmt.Errorf("--receipt takes no arguments, the file and signature are recorded in it")
+	}
+	if cmd.Flags().Changed("bundle") && len(args) > 0 {
+		return fmt.Errorf("--bundle takes no arguments, the file and signature are in it")
+	}
+	if cmd.Flags().Changed("receipt") || cmd.Flags().Changed("bundle") {
+		return nil
+	}
+	if cmd.Flags().Changed("combine") {
+		return cobra.MinimumNArgs(2)(cmd, args)
+	}
+	return cobra.RangeArgs(1, 2)(cmd, args)
+}
+
+// silentArg reports whether the arguments have --silent, which needs to be
+// known before cobra parses the flags.
+func silentArg(args []string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			return false
+		}
+		if arg == "--silent" || arg == "--silent=true" {
+			return true
+		}
+	}
+	return false
+}
+
+// noFang reports whether fang should be bypassed, which needs to be known
+// before cobra parses the flags.
+func noFang(args []string) bool {
+	if v, err := strconv.ParseBool(os.Getenv("SSIGN_NO_FANG")); err == nil && v {
+		return true
+	}
+	for _, arg := range args {
+		if arg == "--" {
+			return false
+		}
+		if arg == "--no-fang" || arg == "--no-fang=true" {
+			return true
+		}
+	}
+	return false
+}
+
 type styles struct {
 	Header lipgloss.Style
 	Text   lipgloss.Style
@@ -173,27 +2473,218 @@ func mustStyles() styles {
 	}
 }
 
+func signMessage(key ssh.Signer, message []byte, namespace string) ([]byte, error) {
+	signer, ok := key.(ssh.AlgorithmSigner)
+	if !ok {
+		return nil, fmt.Errorf("cannot use this key")
+	}
+
+	data, err := sshsig.Sign(signer, rand.Reader, message, namespace)
+	if isAgentRefusal(err) {
+		return nil, fmt.Errorf("could not sign: %w", errKeyLocked)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not sign: %w", err)
+	}
+	return data, nil
+}
+
+func verifyMessage(pub ssh.PublicKey, message, signature []byte, namespace string) error {
+	raw, err := decodeSignature(signature)
+	if err != nil {
+		return fmt.Errorf("could not verify: %w", err)
+	}
+	// the namespace and hash algorithm are checked here, so their errors
+	// don't have to be told apart by the messages of sshsig.Verify.
+	sig, err := parseSignature(signature)
+	if err != nil {
+		return fmt.Errorf("could not verify: %w", err)
+	}
+	if sig.Namespace != namespace {
+		return fmt.Errorf("could not verify: %w: signature namespace is %q, expected %q", errNamespaceMismatch, sig.Namespace, namespace)
+	}
+	if sig.HashAlgorithm != "sha512" {
+		return fmt.Errorf("could not verify: %w: signature hash is %s, only sha512 can be verified", errAlgorithmForbidden, sig.HashAlgorithm)
+	}
+
+	if err := sshsig.Verify(pub, message, raw, namespace); err != nil {
+		return fmt.Errorf("could not verify: %w", withCode(verifyErrorCode(err), err))
+	}
+	return nil
+}
+
+// compareKeygen verifies the signature again with ssh-keygen, after ssign
+// failed to with verr, and reports whether they agree.
+//
+// ssh-keygen only checks the signature itself, so they are expected to
+// disagree when ssign rejected it because of a policy, such as --fips.
+func compareKeygen(cmd *cobra.Command, v verifier, message, signature []byte, verr error) {
+	message, err := v.signedMessage(message, signature)
+	if err != nil {
+		cmd.PrintErrf("Not verifying with ssh-keygen: %v\n", err)
+		return
+	}
+	pubs := v.Pubs
+	if len(pubs) == 0 {
+		sig, err := parseSignature(signature)
+		if err != nil {
+			cmd.PrintErrf("Not verifying with ssh-keygen: %v\n", err)
+			return
+		}
+		pubs = []ssh.PublicKey{sig.PublicKey}
+	}
+
+	out, err := keygenVerify(pubs, message, signature, v.Namespace)
+	switch {
+	case errors.Is(err, errKeygenUnavailable):
+		cmd.PrintErrf("Not verifying with ssh-keygen: %v\n", err)
+	case err == nil:
+		cmd.PrintErrf("ssh-keygen DISAGREES, it accepts the signature ssign rejects (%v): %s\n", verr, out)
+	default:
+		cmd.PrintErrf("ssh-keygen agrees, it rejects the signature too: %s\n", out)
+	}
+}
+
+func printExplanation(cmd *cobra.Command, steps []explainStep) {
+	styles := mustStyles()
+	cmd.PrintErrln()
+	for _, step := range steps {
+		switch {
+		case step.Err != nil:
+			cmd.PrintErrln(styles.Text.Render("✗ " + styles.Code.Render(step.Name) + " " + step.Err.Error()))
+		case step.Skipped != "":
+			cmd.PrintErrln(styles.Text.Render("- " + styles.Code.Render(step.Name) + " not checked, " + step.Skipped))
+		default:
+			cmd.PrintErrln(styles.Text.Render("✓ " + styles.Code.Render(step.Name)))
+		}
+	}
+	cmd.PrintErrln()
+}
+
 func openPublicKey(name string) (ssh.PublicKey, error) {
+	pubs, err := openPublicKeys(name)
+	if err != nil {
+		return nil, err
+	}
+	return pubs[0], nil
+}
+
+// openPublicKeys opens all the keys in the given file, which can either hold
+// one authorized_keys line per key, or a single key in the wire format.
+func openPublicKeys(name string) ([]ssh.PublicKey, error) {
 	in, err := os.ReadFile(name)
 	if err != nil {
 		return nil, err
 	}
 
-	pub, _, _, _, err := ssh.ParseAuthorizedKey(in)
-	if err == nil {
-		return pub, nil
+	var pubs []ssh.PublicKey
+	for rest := in; len(rest) > 0; {
+		var pub ssh.PublicKey
+		pub, _, _, rest, err = ssh.ParseAuthorizedKey(rest)
+		if err != nil {
+			break
+		}
+		pubs = append(pubs, pub)
+	}
+	if len(pubs) > 0 {
+		return pubs, nil
+	}
+
+	pub, err := ssh.ParsePublicKey(in)
+	if err != nil {
+		return nil, err
 	}
-	return ssh.ParsePublicKey(in)
+	return []ssh.PublicKey{pub}, nil
+}
+
+// keySelector picks which key to use from a file holding more than one.
+type keySelector struct {
+	Index       int
+	Fingerprint string
 }
 
-func openPrivateKey(name string) (ssh.Signer, error) {
+func openPrivateKey(name string, sel keySelector, p prompter, ignorePermissions bool) (ssh.Signer, error) {
+	if !ignorePermissions {
+		info, err := os.Stat(name)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", name, err)
+		}
+		if err := checkKeyPermissions(name, info); err != nil {
+			return nil, err
+		}
+	}
 	pemBytes, err := os.ReadFile(name)
 	if err != nil {
 		return nil, fmt.Errorf("key %s: %w", name, err)
 	}
+
+	// the public halves are read first, so only the selected key is
+	// decrypted, and only its passphrase is asked for.
+	var keys []privateKey
+	rest := pemBytes
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if !strings.HasSuffix(block.Type, "PRIVATE KEY") {
+			continue
+		}
+		key, err := peekPrivateKey(name, pem.EncodeToMemory(block), p)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return parsePrivateKey(name, pemBytes, p)
+	}
+
+	pubs := make([]ssh.PublicKey, 0, len(keys))
+	for _, key := range keys {
+		pubs = append(pubs, key.pub)
+	}
+	i, err := selectKey(name, pubs, sel, p)
+	if err != nil {
+		return nil, err
+	}
+	if keys[i].signer != nil {
+		return keys[i].signer, nil
+	}
+	return parsePrivateKey(name, keys[i].pem, p)
+}
+
+// privateKey is a key of a private key file, which might not be decrypted
+// yet.
+type privateKey struct {
+	pem    []byte
+	pub    ssh.PublicKey
+	signer ssh.Signer
+}
+
+// peekPrivateKey reads the public half of the given private key, without
+// decrypting it when the public half is kept in the clear, as in OpenSSH
+// keys. Encrypted PEM keys don't, so their passphrase is asked for.
+func peekPrivateKey(name string, pemBytes []byte, p prompter) (privateKey, error) {
+	signer, err := ssh.ParsePrivateKey(pemBytes)
+	var perr *ssh.PassphraseMissingError
+	if errors.As(err, &perr) && perr.PublicKey != nil {
+		return privateKey{pem: pemBytes, pub: perr.PublicKey}, nil
+	}
+	if err != nil {
+		signer, err = parsePrivateKey(name, pemBytes, p)
+		if err != nil {
+			return privateKey{}, err
+		}
+	}
+	return privateKey{pem: pemBytes, pub: signer.PublicKey(), signer: signer}, nil
+}
+
+func parsePrivateKey(name string, pemBytes []byte, p prompter) (ssh.Signer, error) {
 	result, err := ssh.ParsePrivateKey(pemBytes)
 	if isPassphraseMissing(err) {
-		passphrase, err := ask(name)
+		passphrase, err := ask(p, name)
 		if err != nil {
 			return result, fmt.Errorf("key: %w", err)
 		}
@@ -209,14 +2700,91 @@ func openPrivateKey(name string) (ssh.Signer, error) {
 	return result, nil
 }
 
+// selectKey returns the index of the key to use out of the given ones.
+func selectKey(name string, keys []ssh.PublicKey, sel keySelector, p prompter) (int, error) {
+	if sel.Fingerprint != "" {
+		for i, key := range keys {
+			if ssh.FingerprintSHA256(key) == sel.Fingerprint {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("key: no key with fingerprint %s", sel.Fingerprint)
+	}
+	if sel.Index >= 0 {
+		if sel.Index >= len(keys) {
+			return 0, fmt.Errorf("key: index %d out of range, file has %d keys", sel.Index, len(keys))
+		}
+		return sel.Index, nil
+	}
+	if len(keys) == 1 {
+		return 0, nil
+	}
+	if !p.interactive() {
+		return 0, fmt.Errorf("key: %s has %d keys, pick one with --key-index or --key-fingerprint", name, len(keys))
+	}
+
+	options := make([]huh.Option[int], 0, len(keys))
+	for i, key := range keys {
+		options = append(options, huh.NewOption(
+			fmt.Sprintf("%d: %s %s", i, key.Type(), ssh.FingerprintSHA256(key)),
+			i,
+		))
+	}
+	var idx int
+	if err := p.run(
+		huh.NewSelect[int]().
+			Title(fmt.Sprintf("%s has %d keys, which one should be used?", name, len(keys))).
+			Options(options...).
+			Value(&idx),
+	); err != nil {
+		return 0, fmt.Errorf("key: %w", err)
+	}
+	return idx, nil
+}
+
 func isPassphraseMissing(err error) bool {
 	var kerr *ssh.PassphraseMissingError
 	return errors.As(err, &kerr)
 }
 
-func ask(path string) ([]byte, error) {
+// askFailureAction asks what to do after a verification failure: "explain",
+// "retry" with another key, or "continue".
+func askFailureAction(p prompter, verr error) (string, error) {
+	var action string
+	if err := p.run(
+		huh.NewSelect[string]().
+			Title("Verification failed: "+verr.Error()).
+			Options(
+				huh.NewOption("Show the explanation", "explain"),
+				huh.NewOption("Retry with another public key", "retry"),
+				huh.NewOption("Continue", "continue"),
+			).
+			Value(&action),
+	); err != nil {
+		return "", fmt.Errorf("could not ask what to do: %w", err)
+	}
+	return action, nil
+}
+
+func askPublicKey(p prompter) (string, error) {
+	var name string
+	if err := p.run(
+		huh.NewInput().
+			Inline(true).
+			Value(&name).
+			Title("Public key to verify with: "),
+	); err != nil {
+		return "", fmt.Errorf("could not ask for the public key: %w", err)
+	}
+	return name, nil
+}
+
+func ask(p prompter, path string) ([]byte, error) {
+	if p.PassphraseCommand != "" {
+		return p.passphrase()
+	}
 	var pass string
-	if err := huh.Run(
+	if err := p.run(
 		huh.NewInput().
 			Inline(true).
 			Value(&pass).
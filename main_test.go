@@ -0,0 +1,67 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestOpenPrivateKeyMultiple(t *testing.T) {
+	var data []byte
+	var fingerprints []string
+	for _, passphrase := range []string{"", "secret"} {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var block *pem.Block
+		if passphrase == "" {
+			block, err = ssh.MarshalPrivateKey(key, "")
+		} else {
+			block, err = ssh.MarshalPrivateKeyWithPassphrase(key, "", []byte(passphrase))
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data = append(data, pem.EncodeToMemory(block)...)
+		// a block which is not a key must be skipped.
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("x")})...)
+		pub, err := ssh.NewPublicKey(key.Public())
+		if err != nil {
+			t.Fatal(err)
+		}
+		fingerprints = append(fingerprints, ssh.FingerprintSHA256(pub))
+	}
+	name := filepath.Join(t.TempDir(), "keys")
+	if err := os.WriteFile(name, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("unencrypted", func(t *testing.T) {
+		// the passphrase of the encrypted key must not be asked for.
+		p := prompter{Out: os.Stderr, PassphraseCommand: "false"}
+		key, err := openPrivateKey(name, keySelector{Index: -1, Fingerprint: fingerprints[0]}, p, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fp := ssh.FingerprintSHA256(key.PublicKey()); fp != fingerprints[0] {
+			t.Errorf("expected %s, got %s", fingerprints[0], fp)
+		}
+	})
+
+	t.Run("encrypted", func(t *testing.T) {
+		p := prompter{Out: os.Stderr, PassphraseCommand: "echo secret"}
+		key, err := openPrivateKey(name, keySelector{Index: 1}, p, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fp := ssh.FingerprintSHA256(key.PublicKey()); fp != fingerprints[1] {
+			t.Errorf("expected %s, got %s", fingerprints[1], fp)
+		}
+	})
+}
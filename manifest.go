@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// manifestName is the name of the manifest written in the listed directory.
+const manifestName = "ssign.manifest"
+
+var (
+	errInvalidManifest  = errors.New("invalid manifest")
+	errManifestMismatch = errors.New("files do not match the manifest")
+	errUnsafePath       = errors.New("unsafe path")
+)
+
+// manifestEntry is a file listed in a manifest, with its SHA256 digest.
+type manifestEntry struct {
+	Path   string
+	Digest string
+}
+
+// manifestCompare returns the function comparing paths in the given sort
+// order: "byte", comparing the UTF-8 bytes, like sha256sum and "LC_ALL=C
+// sort", or "unicode", using the Unicode collation algorithm.
+//
+// The order must be fixed for a manifest, and so its signature, to be
+// reproducible: the same files must always give the same bytes.
+func manifestCompare(order string) (func(a, b string) int, error) {
+	switch order {
+	case "byte":
+		return strings.Compare, nil
+	case "unicode":
+		c := collate.New(language.Und)
+		return func(a, b string) int {
+			// paths the collation finds equal are still ordered, so the
+			// order is total.
+			if n := c.CompareString(a, b); n != 0 {
+				return n
+			}
+			return strings.Compare(a, b)
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid sort order %q, expected byte or unicode", order)
+	}
+}
+
+// buildManifest lists and hashes all the regular files under dir, except for
+// the manifest itself and its signature, sorted in the given order.
+func buildManifest(dir, order string) ([]manifestEntry, error) {
+	compare, err := manifestCompare(order)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := listManifestFiles(dir, manifestName, manifestName+".ssig")
+	if err != nil {
+		return nil, err
+	}
+	fsys := os.DirFS(dir)
+	entries := make([]manifestEntry, 0, len(files))
+	for _, file := range files {
+		digest, err := hashFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, manifestEntry{Path: file, Digest: digest})
+	}
+	slices.SortFunc(entries, func(a, b manifestEntry) int {
+		return compare(a.Path, b.Path)
+	})
+	return entries, nil
+}
+
+// listManifestFiles lists the regular files under dir, as slash separated
+// relative paths, except for the excluded ones, e.g. the manifest and its
+// signature.
+func listManifestFiles(dir string, exclude ...string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if slices.Contains(exclude, rel) {
+			return nil
+		}
+		files = append(files, rel)
+		return nil
+	})
+	return files, err
+}
+
+// checkManifestOrder checks that the entries are sorted in the given order,
+// as a manifest generated in another order would not be reproducible.
+func checkManifestOrder(entries []manifestEntry, order string) error {
+	compare, err := manifestCompare(order)
+	if err != nil {
+		return err
+	}
+	for i := 1; i < len(entries); i++ {
+		if compare(entries[i-1].Path, entries[i].Path) > 0 {
+			return fmt.Errorf("%w: %s is listed before %s, which is not in %s order", errInvalidManifest, entries[i-1].Path, entries[i].Path, order)
+		}
+	}
+	return nil
+}
+
+// encodeManifest writes the entries in the format used by sha256sum, so the
+// manifest can also be checked with "sha256sum -c".
+func encodeManifest(entries []manifestEntry) []byte {
+	var buf bytes.Buffer
+	for _, e := range entries {
+		fmt.Fprintf(&buf, "%s  %s\n", e.Digest, e.Path)
+	}
+	return buf.Bytes()
+}
+
+// parseManifest parses a manifest written by [encodeManifest], rejecting
+// absolute paths and paths going up with "..", including with Windows
+// separators, as manifests use slashes.
+func parseManifest(data []byte) ([]manifestEntry, error) {
+	var entries []manifestEntry
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; s.Scan(); n++ {
+		digest, path, ok := strings.Cut(s.Text(), "  ")
+		if !ok || path == "" || len(digest) != sha256.Size*2 {
+			return nil, fmt.Errorf("%w: line %d", errInvalidManifest, n)
+		}
+		if _, err := hex.DecodeString(digest); err != nil {
+			return nil, fmt.Errorf("%w: line %d", errInvalidManifest, n)
+		}
+		// a manifest must not make verify read files outside of its
+		// directory.
+		if strings.Contains(path, `\`) || !filepath.IsLocal(filepath.FromSlash(path)) {
+			return nil, fmt.Errorf("%w: line %d: %q escapes the manifest directory", errUnsafePath, n, path)
+		}
+		entries = append(entries, manifestEntry{Path: path, Digest: digest})
+	}
+	return entries, s.Err()
+}
+
+// checkManifest hashes the listed files again, relative to the directory of
+// the named manifest, and fails with the files which are missing or were
+// modified. When exact, files not listed in the manifest also fail it, other
+// than the manifest and its signature, sigName or the default one.
+func checkManifest(name, sigName string, entries []manifestEntry, exact bool) error {
+	dir := filepath.Dir(name)
+	// reading through a root also keeps symbolic links from pointing
+	// outside of the directory.
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	for _, e := range entries {
+		// the root would fail to open such links anyway, but with an error
+		// that doesn't tell it's a security issue.
+		if real, err := filepath.EvalSymlinks(filepath.Join(dir, filepath.FromSlash(e.Path))); err == nil {
+			if rel, err := filepath.Rel(realDir, real); err != nil || !filepath.IsLocal(rel) {
+				return fmt.Errorf("%w: %q links outside of the manifest directory", errUnsafePath, e.Path)
+			}
+		}
+		digest, err := hashFile(root.FS(), e.Path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			problems = append(problems, e.Path+" is missing")
+		case err != nil:
+			return fmt.Errorf("could not hash %s: %w", e.Path, err)
+		case digest != e.Digest:
+			problems = append(problems, e.Path+" was modified")
+		}
+	}
+	if exact {
+		exclude := []string{filepath.Base(name), filepath.Base(name) + ".ssig"}
+		if rel, err := filepath.Rel(dir, sigName); err == nil && filepath.IsLocal(rel) {
+			exclude = append(exclude, filepath.ToSlash(rel))
+		}
+		files, err := listManifestFiles(dir, exclude...)
+		if err != nil {
+			return fmt.Errorf("could not list files in %s: %w", dir, err)
+		}
+		listed := make(map[string]bool, len(entries))
+		for _, e := range entries {
+			listed[e.Path] = true
+		}
+		for _, file := range files {
+			if !listed[file] {
+				problems = append(problems, file+" is not listed")
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", errManifestMismatch, strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+func hashFile(fsys fs.FS, name string) (string, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestManifestUnsafePaths(t *testing.T) {
+	// the manifest is in tmp/d/m, and every path below resolves, or would on
+	// Windows, to tmp/d/x, outside of it.
+	tmp := t.TempDir()
+	dir := filepath.Join(tmp, "d", "m")
+	if err := os.MkdirAll(filepath.Join(dir, "a"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	secret := filepath.Join(tmp, "d", "x")
+	if err := os.WriteFile(secret, []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte("secret"))
+	digest := hex.EncodeToString(sum[:])
+	if err := os.Symlink(secret, filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(tmp, "d"), filepath.Join(dir, "a", "up")); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, path := range map[string]string{
+		"parent":            "../x",
+		"absolute":          filepath.ToSlash(secret),
+		"parent in middle":  "a/../../x",
+		"windows parent":    `..\x`,
+		"windows absolute":  `C:\x`,
+		"symlink":           "link",
+		"symlink directory": "a/up/x",
+	} {
+		t.Run(name, func(t *testing.T) {
+			entries, err := parseManifest([]byte(digest + "  " + path + "\n"))
+			if err == nil {
+				err = checkManifest(filepath.Join(dir, manifestName), "", entries, false)
+			}
+			if !errors.Is(err, errUnsafePath) {
+				t.Fatalf("expected an unsafe path error, got %v", err)
+			}
+
+			// even if the manifest was not parsed first, the file outside
+			// of the directory must not be read: it would match its digest.
+			err = checkManifest(filepath.Join(dir, manifestName), "", []manifestEntry{{Path: path, Digest: digest}}, false)
+			if err == nil {
+				t.Fatalf("expected %q not to be read", path)
+			}
+		})
+	}
+}
+
+func TestManifestLocalPaths(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "a", "b"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a", "x"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../x", filepath.Join(dir, "a", "b", "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := buildManifest(dir, "byte")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries = append(entries, manifestEntry{Path: "a/b/link", Digest: entries[0].Digest})
+	parsed, err := parseManifest(encodeManifest(entries))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkManifest(filepath.Join(dir, manifestName), "", parsed, false); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// A manifest signed with "sign --key-id release" is verified with its
+// MANIFEST.release.ssig signature, which --exact must not report.
+func TestManifestExactSignature(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "x"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := buildManifest(dir, "byte")
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Join(dir, manifestName)
+	sigName := name + ".release.ssig"
+	for _, file := range []string{name, sigName} {
+		if err := os.WriteFile(file, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := checkManifest(name, sigName, entries, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkManifest(name, name+".ssig", entries, true); !errors.Is(err, errManifestMismatch) {
+		t.Fatalf("expected %s to be reported as not listed, got %v", filepath.Base(sigName), err)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// batchMetrics are the counters of a directory verification, written by
+// --metrics.
+type batchMetrics struct {
+	Files   int
+	Passed  int
+	Failed  int
+	Bytes   int64
+	Elapsed time.Duration
+}
+
+func newBatchMetrics(results []batchResult, elapsed time.Duration) batchMetrics {
+	m := batchMetrics{
+		Files:   len(results),
+		Failed:  batchFailures(results),
+		Elapsed: elapsed,
+	}
+	m.Passed = m.Files - m.Failed
+	for _, r := range results {
+		m.Bytes += r.Size
+	}
+	return m
+}
+
+// writePrometheus writes the metrics in the Prometheus text format, e.g. for
+// the node_exporter textfile collector.
+func (m batchMetrics) writePrometheus(w io.Writer) error {
+	metrics := []struct {
+		name, kind, help string
+		value            any
+	}{
+		{"ssign_verify_files", "gauge", "Files processed.", m.Files},
+		{"ssign_verify_passed", "gauge", "Files with a valid signature.", m.Passed},
+		{"ssign_verify_failed", "gauge", "Files with a missing or invalid signature.", m.Failed},
+		{"ssign_verify_bytes_hashed", "gauge", "Bytes read from the verified files.", m.Bytes},
+		{"ssign_verify_duration_seconds", "gauge", "Time taken to verify all files.", m.Elapsed.Seconds()},
+	}
+	for _, metric := range metrics {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", metric.name, metric.help, metric.name, metric.kind, metric.name, metric.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"encoding/pem"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// migration renames a signature, possibly re-encoding it.
+type migration struct {
+	From string
+	To   string
+	Data []byte
+}
+
+// findMigrations lists the signatures under dir with the given extension.
+func findMigrations(dir, from, to string) ([]*migration, error) {
+	var migrations []*migration
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, from) {
+			return nil
+		}
+		migrations = append(migrations, &migration{
+			From: path,
+			To:   strings.TrimSuffix(path, from) + to,
+		})
+		return nil
+	})
+	return migrations, err
+}
+
+// prepare re-encodes the signature in the given format, making sure it still
+// decodes to the same signature.
+func (m *migration) prepare(format string) error {
+	if m.From != m.To {
+		if _, err := os.Stat(m.To); err == nil {
+			return fmt.Errorf("%s already exists", m.To)
+		}
+	}
+
+	in, err := os.ReadFile(m.From)
+	if err != nil {
+		return err
+	}
+	raw, err := decodeSignature(in)
+	if err != nil {
+		return err
+	}
+	var headers map[string]string
+	if block, _ := pem.Decode(in); block != nil {
+		headers = block.Headers
+	}
+	out, err := encodeSignature(raw, format, headers)
+	if err != nil {
+		return err
+	}
+	if check, err := decodeSignature(out); err != nil || !bytes.Equal(check, raw) {
+		return fmt.Errorf("migrated signature does not match the original")
+	}
+	m.Data = out
+	return nil
+}
+
+// write writes the prepared signature into its new name, and then removes the
+// old one.
+//
+// The new file is written to a temporary file and renamed into place, so it
+// is either fully written or not there at all.
+func (m *migration) write() error {
+	tmp, err := os.CreateTemp(filepath.Dir(m.To), ".ssign-migrate-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(m.Data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), m.To); err != nil {
+		return err
+	}
+	if m.From != m.To {
+		return os.Remove(m.From)
+	}
+	return nil
+}
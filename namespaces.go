@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// namespaceRule is a file pattern, matched like the --require-signed
+// patterns of scan, and the namespace the matching files are signed in.
+type namespaceRule struct {
+	Pattern   string
+	Namespace string
+}
+
+// namespaceMap gives the namespace of each file of a directory, for trees
+// signed by different tools.
+type namespaceMap struct {
+	// Dir is the directory the patterns are relative to.
+	Dir   string
+	Rules []namespaceRule
+}
+
+// readNamespaceMap reads a JSON object mapping file patterns to namespaces,
+// e.g. {"*.tar.gz": "file", "docs/*": "docs@example.com"}. The rules keep the
+// order of the object, as the first matching one is used.
+func readNamespaceMap(name, dir string) (namespaceMap, error) {
+	m := namespaceMap{Dir: dir}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return m, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return m, fmt.Errorf("%w: expected an object of patterns and namespaces", errInvalidJSON)
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return m, fmt.Errorf("%w: %w", errInvalidJSON, err)
+		}
+		pattern := tok.(string)
+		var namespace string
+		if err := dec.Decode(&namespace); err != nil {
+			return m, fmt.Errorf("%w: namespace of %q: %w", errInvalidJSON, pattern, err)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return m, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		if namespace == "" {
+			return m, fmt.Errorf("%w: empty namespace for %q", errInvalidJSON, pattern)
+		}
+		m.Rules = append(m.Rules, namespaceRule{pattern, namespace})
+	}
+	if _, err := dec.Token(); err != nil {
+		return m, fmt.Errorf("%w: %w", errInvalidJSON, err)
+	}
+	return m, nil
+}
+
+// namespace returns the namespace of the first rule matching file, or
+// fallback if none does.
+func (m namespaceMap) namespace(file, fallback string) string {
+	rel, err := filepath.Rel(m.Dir, file)
+	if err != nil {
+		return fallback
+	}
+	for _, rule := range m.Rules {
+		if matchPattern(rule.Pattern, filepath.ToSlash(rel)) {
+			return rule.Namespace
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+var (
+	errObjectStorageUnsupported = errors.New("ssign was built without support for this object storage")
+	errObjectExists             = errors.New("object already exists")
+)
+
+// readObject reads an object from S3 (s3://bucket/key) or Google Cloud
+// Storage (gs://bucket/object), authenticating with the standard credential
+// chain of each provider.
+func readObject(ctx context.Context, rawURL string, size byteSize) ([]byte, error) {
+	scheme, bucket, key, err := parseObjectURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var r io.ReadCloser
+	switch scheme {
+	case "s3":
+		r, err = openS3Object(ctx, bucket, key)
+	case "gs":
+		r, err = openGCSObject(ctx, bucket, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var buf bytes.Buffer
+	n := min(max(int(size), minReadBufferSize), maxReadBufferSize)
+	if _, err := io.Copy(&buf, bufio.NewReaderSize(r, n)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeObject uploads data to an object of S3 or Google Cloud Storage, as
+// [readObject] reads them. Unless force is set, the upload is conditional,
+// failing with [errObjectExists] if the object exists, so concurrent uploads
+// can't overwrite each other either.
+func writeObject(ctx context.Context, rawURL string, data []byte, force bool) error {
+	scheme, bucket, key, err := parseObjectURL(rawURL)
+	if err != nil {
+		return err
+	}
+	switch scheme {
+	case "s3":
+		return putS3Object(ctx, bucket, key, data, force)
+	default:
+		return putGCSObject(ctx, bucket, key, data, force)
+	}
+}
+
+// parseObjectURL splits an s3://bucket/key or gs://bucket/object URL.
+func parseObjectURL(rawURL string) (scheme, bucket, key string, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", "", err
+	}
+	bucket, key = u.Host, strings.TrimPrefix(u.Path, "/")
+	if bucket == "" || key == "" || (u.Scheme != "s3" && u.Scheme != "gs") {
+		return "", "", "", fmt.Errorf("invalid object URL %q, expected s3://bucket/key or gs://bucket/object", rawURL)
+	}
+	return u.Scheme, bucket, key, nil
+}
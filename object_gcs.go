@@ -0,0 +1,76 @@
+//go:build gcs
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/oauth2/google"
+)
+
+func openGCSObject(ctx context.Context, bucket, object string) (io.ReadCloser, error) {
+	client, err := google.DefaultClient(ctx, "https://www.googleapis.com/auth/devstorage.read_only")
+	if err != nil {
+		return nil, err
+	}
+	u := fmt.Sprintf("https://storage.googleapis.com/storage/v1/b/%s/o/%s?alt=media", url.PathEscape(bucket), url.PathEscape(object))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("access denied (%s), check the application default credentials", resp.Status)
+	case http.StatusNotFound:
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("object not found (%s)", resp.Status)
+	default:
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+}
+
+func putGCSObject(ctx context.Context, bucket, object string, data []byte, force bool) error {
+	client, err := google.DefaultClient(ctx, "https://www.googleapis.com/auth/devstorage.read_write")
+	if err != nil {
+		return err
+	}
+	query := url.Values{"uploadType": {"media"}, "name": {object}}
+	if !force {
+		// generation 0 matches only objects that don't exist.
+		query.Set("ifGenerationMatch", "0")
+	}
+	u := fmt.Sprintf("https://storage.googleapis.com/upload/storage/v1/b/%s/o?%s", url.PathEscape(bucket), query.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusPreconditionFailed:
+		return fmt.Errorf("%w, use --force to overwrite it", errObjectExists)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("access denied (%s), check the application default credentials", resp.Status)
+	default:
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !gcs
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+func openGCSObject(context.Context, string, string) (io.ReadCloser, error) {
+	return nil, fmt.Errorf("%w, build it with -tags gcs", errObjectStorageUnsupported)
+}
+
+func putGCSObject(context.Context, string, string, []byte, bool) error {
+	return fmt.Errorf("%w, build it with -tags gcs", errObjectStorageUnsupported)
+}
@@ -0,0 +1,17 @@
+//go:build !s3
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+func openS3Object(context.Context, string, string) (io.ReadCloser, error) {
+	return nil, fmt.Errorf("%w, build it with -tags s3", errObjectStorageUnsupported)
+}
+
+func putS3Object(context.Context, string, string, []byte, bool) error {
+	return fmt.Errorf("%w, build it with -tags s3", errObjectStorageUnsupported)
+}
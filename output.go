@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// jsonResult is the output of --json.
+type jsonResult struct {
+	result
+	OK           bool      `json:"ok"`
+	Warning      string    `json:"warning,omitempty"`
+	SignatureAge string    `json:"signature_age,omitempty"`
+	KeyExpires   string    `json:"key_expires,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	ErrorCode    errorCode `json:"error_code,omitempty"`
+}
+
+func printJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// printJSONError prints the given error, and its code, as a failed result,
+// without its paths if redact is set.
+func printJSONError(w io.Writer, r result, err error, redact bool) {
+	jr := jsonResult{
+		result:    r,
+		Error:     err.Error(),
+		ErrorCode: codeOf(err),
+	}
+	if redact {
+		jr = jr.redacted()
+	}
+	_ = printJSON(w, jr)
+}
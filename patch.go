@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+var errInvalidPatch = errors.New("invalid patch")
+
+const bsdiffMagic = "BSDIFF40"
+
+// applyPatch applies a patch in the format of bsdiff 4 (starting with
+// "BSDIFF40", as written by the bsdiff tool and most of its ports) to the
+// base file, and returns the patched content.
+func applyPatch(baseName, patchName string) ([]byte, error) {
+	base, err := os.ReadFile(baseName)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := os.ReadFile(patchName)
+	if err != nil {
+		return nil, err
+	}
+	return bspatch(base, patch)
+}
+
+// bspatch applies a bsdiff 4 patch: a 32 bytes header, followed by the
+// bzip2 compressed control, diff, and extra blocks. The control block is a
+// list of triples: how many bytes to add from the diff block to the old ones,
+// how many to copy from the extra block, and how far to seek in the old
+// bytes.
+func bspatch(old, patch []byte) ([]byte, error) {
+	if len(patch) < 32 || string(patch[:8]) != bsdiffMagic {
+		return nil, fmt.Errorf("%w: not a %s patch", errInvalidPatch, bsdiffMagic)
+	}
+	ctrlLen, dataLen, newSize := offtin(patch[8:]), offtin(patch[16:]), offtin(patch[24:])
+	if ctrlLen < 0 || dataLen < 0 || newSize < 0 || ctrlLen > int64(len(patch)-32) || dataLen > int64(len(patch)-32)-ctrlLen {
+		return nil, fmt.Errorf("%w: bad header", errInvalidPatch)
+	}
+	ctrl := bzip2.NewReader(bytes.NewReader(patch[32 : 32+ctrlLen]))
+	diff := bzip2.NewReader(bytes.NewReader(patch[32+ctrlLen : 32+ctrlLen+dataLen]))
+	extra := bzip2.NewReader(bytes.NewReader(patch[32+ctrlLen+dataLen:]))
+
+	// the new content grows as it's read, so a bogus size in the header
+	// can't make it allocate more than the patch really has.
+	var out bytes.Buffer
+	var oldPos int64
+	triple := make([]byte, 24)
+	for int64(out.Len()) < newSize {
+		if _, err := io.ReadFull(ctrl, triple); err != nil {
+			return nil, fmt.Errorf("%w: control block: %w", errInvalidPatch, err)
+		}
+		add, copyLen, seek := offtin(triple), offtin(triple[8:]), offtin(triple[16:])
+		if add < 0 || copyLen < 0 || add > newSize-int64(out.Len()) {
+			return nil, fmt.Errorf("%w: corrupt control block", errInvalidPatch)
+		}
+
+		start := out.Len()
+		if _, err := io.CopyN(&out, diff, add); err != nil {
+			return nil, fmt.Errorf("%w: diff block: %w", errInvalidPatch, err)
+		}
+		added := out.Bytes()[start:]
+		for i := range added {
+			if p := oldPos + int64(i); p >= 0 && p < int64(len(old)) {
+				added[i] += old[p]
+			}
+		}
+		oldPos += add
+
+		if copyLen > newSize-int64(out.Len()) {
+			return nil, fmt.Errorf("%w: corrupt control block", errInvalidPatch)
+		}
+		if _, err := io.CopyN(&out, extra, copyLen); err != nil {
+			return nil, fmt.Errorf("%w: extra block: %w", errInvalidPatch, err)
+		}
+		oldPos += seek
+	}
+	return out.Bytes(), nil
+}
+
+// offtin decodes the sign and magnitude little endian integers of bsdiff.
+func offtin(b []byte) int64 {
+	n := int64(binary.LittleEndian.Uint64(b) &^ (1 << 63))
+	if b[7]&0x80 != 0 {
+		return -n
+	}
+	return n
+}
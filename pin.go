@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	errInvalidPin  = errors.New("invalid key pin")
+	errPinMismatch = errors.New("key pin mismatch")
+)
+
+// pinPrefix starts the key pin in a signature file name.
+const pinPrefix = ".SHA256-"
+
+// filenamePin returns the fingerprint of the key pinned by the name of a
+// signature file, e.g. "app.bin.SHA256-<hash>.ssig", where the hash is the
+// SHA256 of the key, as in its fingerprint, but in URL-safe base64 as "/"
+// can't be in a file name.
+func filenamePin(sigName string) (string, error) {
+	base := filepath.Base(sigName)
+	i := strings.LastIndex(base, pinPrefix)
+	if i < 0 {
+		return "", fmt.Errorf("%w: %s has no %s<hash> in its name", errInvalidPin, base, pinPrefix[1:])
+	}
+	hash, _, _ := strings.Cut(base[i+len(pinPrefix):], ".")
+	sum, err := base64.RawURLEncoding.DecodeString(strings.ReplaceAll(hash, "+", "-"))
+	if err != nil || len(sum) != 32 {
+		return "", fmt.Errorf("%w: %q in %s is not a base64 SHA256", errInvalidPin, hash, base)
+	}
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum), nil
+}
+
+// checkFilenamePin checks that the signature was made by the key its file
+// name pins.
+//
+// The name is chosen by whoever publishes the signature, so the pin only
+// narrows down keys that are already trusted: the signature must have been
+// verified with them, not with its embedded key.
+func checkFilenamePin(res result) error {
+	pin, err := filenamePin(res.Signature)
+	if err != nil {
+		return err
+	}
+	if res.Fingerprint != pin {
+		return fmt.Errorf("%w: %s was signed by %s, its name pins %s", errPinMismatch, res.File, res.Fingerprint, pin)
+	}
+	return nil
+}
+
+// fingerprintFileSuffix is appended to the file name for the fingerprint
+// file of --emit-fingerprint-file.
+const fingerprintFileSuffix = ".fpr"
+
+// readFingerprintFile reads a key fingerprint file, as written by "ssign sign
+// --emit-fingerprint-file".
+//
+// A fingerprint file next to the signed file is only as trustworthy as where
+// it came from: whoever can publish the signature can also publish a
+// fingerprint file matching the key they signed with.
+func readFingerprintFile(name string) (string, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+	fpr := strings.TrimSpace(string(data))
+	hash, ok := strings.CutPrefix(fpr, "SHA256:")
+	if sum, err := base64.RawStdEncoding.DecodeString(hash); !ok || err != nil || len(sum) != 32 {
+		return "", fmt.Errorf("%w: %q is not a SHA256 fingerprint", errInvalidPin, fpr)
+	}
+	return fpr, nil
+}
+
+// checkFingerprintFile checks that the signature was made by the key of the
+// named fingerprint file.
+func checkFingerprintFile(res result, pin, name string) error {
+	if res.Fingerprint != pin {
+		return fmt.Errorf("%w: %s was signed by %s, %s pins %s", errPinMismatch, res.File, res.Fingerprint, name, pin)
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+package main
+
+import "errors"
+
+var (
+	errPKCS11Unsupported = errors.New("ssign was built without PKCS#11 support, build it with -tags pkcs11")
+	errPKCS11KeyNotFound = errors.New("no public key with this label on the token")
+)
@@ -0,0 +1,9 @@
+//go:build !pkcs11
+
+package main
+
+import "golang.org/x/crypto/ssh"
+
+func openPKCS11PublicKey(string, string) (ssh.PublicKey, error) {
+	return nil, errPKCS11Unsupported
+}
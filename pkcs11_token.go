@@ -0,0 +1,155 @@
+//go:build pkcs11
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/asn1"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/miekg/pkcs11"
+	"golang.org/x/crypto/ssh"
+)
+
+// ckkECEdwards is CKK_EC_EDWARDS, from PKCS#11 v3.0.
+const ckkECEdwards = 0x40
+
+// openPKCS11PublicKey finds the public key with the given label in the slots
+// of the given PKCS#11 module.
+func openPKCS11PublicKey(module, label string) (ssh.PublicKey, error) {
+	p := pkcs11.New(module)
+	if p == nil {
+		return nil, fmt.Errorf("could not load PKCS#11 module %s", module)
+	}
+	defer p.Destroy()
+	if err := p.Initialize(); err != nil {
+		return nil, fmt.Errorf("could not initialize PKCS#11 module %s: %w", module, err)
+	}
+	defer p.Finalize()
+
+	slots, err := p.GetSlotList(true)
+	if err != nil {
+		return nil, fmt.Errorf("could not list PKCS#11 slots: %w", err)
+	}
+	for _, slot := range slots {
+		pub, err := findPKCS11PublicKey(p, slot, label)
+		if err != nil {
+			return nil, err
+		}
+		if pub != nil {
+			return ssh.NewPublicKey(pub)
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", errPKCS11KeyNotFound, label)
+}
+
+func findPKCS11PublicKey(p *pkcs11.Ctx, slot uint, label string) (any, error) {
+	session, err := p.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION)
+	if err != nil {
+		return nil, fmt.Errorf("could not open PKCS#11 session: %w", err)
+	}
+	defer p.CloseSession(session)
+
+	if err := p.FindObjectsInit(session, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PUBLIC_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+	}); err != nil {
+		return nil, fmt.Errorf("could not search PKCS#11 token: %w", err)
+	}
+	objects, _, err := p.FindObjects(session, 1)
+	_ = p.FindObjectsFinal(session)
+	if err != nil {
+		return nil, fmt.Errorf("could not search PKCS#11 token: %w", err)
+	}
+	if len(objects) == 0 {
+		return nil, nil
+	}
+
+	attrs, err := p.GetAttributeValue(session, objects[0], []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, nil),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not read PKCS#11 key type: %w", err)
+	}
+	keyType := pkcs11Ulong(attrs[0].Value)
+
+	switch keyType {
+	case pkcs11.CKK_RSA:
+		attrs, err := p.GetAttributeValue(session, objects[0], []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_MODULUS, nil),
+			pkcs11.NewAttribute(pkcs11.CKA_PUBLIC_EXPONENT, nil),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not read PKCS#11 RSA key: %w", err)
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(attrs[0].Value),
+			E: int(new(big.Int).SetBytes(attrs[1].Value).Int64()),
+		}, nil
+	case pkcs11.CKK_EC, ckkECEdwards:
+		attrs, err := p.GetAttributeValue(session, objects[0], []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, nil),
+			pkcs11.NewAttribute(pkcs11.CKA_EC_POINT, nil),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not read PKCS#11 EC key: %w", err)
+		}
+		var point []byte
+		if _, err := asn1.Unmarshal(attrs[1].Value, &point); err != nil {
+			return nil, fmt.Errorf("could not parse PKCS#11 EC point: %w", err)
+		}
+		if keyType == ckkECEdwards {
+			if len(point) != ed25519.PublicKeySize {
+				return nil, fmt.Errorf("unsupported PKCS#11 Edwards curve")
+			}
+			return ed25519.PublicKey(point), nil
+		}
+		curve, err := pkcs11Curve(attrs[0].Value)
+		if err != nil {
+			return nil, err
+		}
+		x, y := elliptic.Unmarshal(curve, point)
+		if x == nil {
+			return nil, fmt.Errorf("could not parse PKCS#11 EC point")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported PKCS#11 key type %#x", keyType)
+	}
+}
+
+// pkcs11Curve returns the curve of the given DER encoded CKA_EC_PARAMS.
+func pkcs11Curve(params []byte) (elliptic.Curve, error) {
+	var oid asn1.ObjectIdentifier
+	if _, err := asn1.Unmarshal(params, &oid); err != nil {
+		return nil, fmt.Errorf("could not parse PKCS#11 EC params: %w", err)
+	}
+	switch {
+	case oid.Equal(asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}):
+		return elliptic.P256(), nil
+	case oid.Equal(asn1.ObjectIdentifier{1, 3, 132, 0, 34}):
+		return elliptic.P384(), nil
+	case oid.Equal(asn1.ObjectIdentifier{1, 3, 132, 0, 35}):
+		return elliptic.P521(), nil
+	default:
+		return nil, fmt.Errorf("unsupported PKCS#11 EC curve %s", oid)
+	}
+}
+
+// pkcs11Ulong decodes a CK_ULONG attribute, which is in the native byte
+// order and size.
+func pkcs11Ulong(b []byte) uint64 {
+	switch len(b) {
+	case 4:
+		return uint64(binary.NativeEndian.Uint32(b))
+	case 8:
+		return binary.NativeEndian.Uint64(b)
+	default:
+		return 0
+	}
+}
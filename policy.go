@@ -0,0 +1,118 @@
+package main
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	errNamespaceMismatch   = errors.New("namespace mismatch")
+	errNamespaceDeprecated = errors.New("namespace deprecated")
+	errAlgorithmForbidden  = errors.New("algorithm not allowed")
+	errKeyTooSmall         = errors.New("key too small")
+	errReservedMismatch    = errors.New("reserved field mismatch")
+)
+
+// fipsAlgorithms are the signature algorithms accepted by --fips by default:
+// RSA with SHA-2, and ECDSA over the NIST curves.
+//
+// Ed25519 is approved by FIPS 186-5, but not by older policies, so it needs
+// to be allowed explicitly with --fips-algorithms.
+var fipsAlgorithms = []string{
+	ssh.KeyAlgoRSASHA256,
+	ssh.KeyAlgoRSASHA512,
+	ssh.KeyAlgoECDSA256,
+	ssh.KeyAlgoECDSA384,
+	ssh.KeyAlgoECDSA521,
+}
+
+// policy holds the extra checks verify enforces on a signature, on top of
+// its cryptographic validity.
+type policy struct {
+	Namespace            string
+	StrictNamespace      bool
+	DeprecatedNamespaces []string
+	FIPS                 bool
+	FIPSAlgorithms       []string
+	MinRSABits           int
+	RequireHash          string
+	KeyAlgorithm         string
+	// Reserved, when CheckReserved is set, is the value the reserved field
+	// of the signature must have. It is not covered by the signature: the
+	// signed data always has it empty, as in OpenSSH.
+	Reserved      string
+	CheckReserved bool
+	// AllowSHA1 accepts ssh-rsa signatures, which hash with SHA-1.
+	AllowSHA1 bool
+}
+
+// check checks the given signature against the policy.
+func (p policy) check(sig *signature) error {
+	if p.StrictNamespace && sig.Namespace != p.Namespace {
+		return fmt.Errorf("%w: signature namespace is %q, expected %q", errNamespaceMismatch, sig.Namespace, p.Namespace)
+	}
+	if slices.Contains(p.DeprecatedNamespaces, sig.Namespace) {
+		return fmt.Errorf("%w: signature was made under %q", errNamespaceDeprecated, sig.Namespace)
+	}
+	if sig.Signature.Format == ssh.KeyAlgoRSA && !p.AllowSHA1 {
+		return fmt.Errorf("%w: %s signatures use SHA-1, which is broken, re-sign with rsa-sha2-512 or use --allow-sha1", errAlgorithmForbidden, sig.Signature.Format)
+	}
+	if p.FIPS && !slices.Contains(p.FIPSAlgorithms, sig.Signature.Format) {
+		return fmt.Errorf("%w: %s is not in the FIPS allowed set (%s)", errAlgorithmForbidden, sig.Signature.Format, strings.Join(p.FIPSAlgorithms, ", "))
+	}
+	if p.RequireHash != "" && sig.HashAlgorithm != p.RequireHash {
+		return fmt.Errorf("%w: signature hash is %s, %s is required", errAlgorithmForbidden, sig.HashAlgorithm, p.RequireHash)
+	}
+	if p.KeyAlgorithm != "" && keyAlgorithm(sig.PublicKey) != p.KeyAlgorithm {
+		return fmt.Errorf("%w: signature was made with a %s key, %s is required", errAlgorithmForbidden, keyAlgorithm(sig.PublicKey), p.KeyAlgorithm)
+	}
+	if bits, ok := rsaBits(sig.PublicKey); ok && bits < p.MinRSABits {
+		return fmt.Errorf("%w: RSA key has %d bits, at least %d are required", errKeyTooSmall, bits, p.MinRSABits)
+	}
+	if p.CheckReserved && sig.Reserved != p.Reserved {
+		return fmt.Errorf("%w: signature reserved field is %q, expected %q", errReservedMismatch, sig.Reserved, p.Reserved)
+	}
+	return nil
+}
+
+// keyAlgorithm returns the algorithm of the given key, or of the key it
+// certifies if it's a certificate.
+func keyAlgorithm(pub ssh.PublicKey) string {
+	if cert, ok := pub.(*ssh.Certificate); ok {
+		return cert.Key.Type()
+	}
+	return pub.Type()
+}
+
+// keysWithAlgorithm returns the keys of the given algorithm.
+func keysWithAlgorithm(pubs []ssh.PublicKey, algorithm string) []ssh.PublicKey {
+	var keys []ssh.PublicKey
+	for _, pub := range pubs {
+		if keyAlgorithm(pub) == algorithm {
+			keys = append(keys, pub)
+		}
+	}
+	return keys
+}
+
+// rsaBits returns the modulus size of the given key, or of the key it
+// certifies if it's a certificate, if it's an RSA key.
+func rsaBits(pub ssh.PublicKey) (int, bool) {
+	if cert, ok := pub.(*ssh.Certificate); ok {
+		pub = cert.Key
+	}
+	cpub, ok := pub.(ssh.CryptoPublicKey)
+	if !ok {
+		return 0, false
+	}
+	rpub, ok := cpub.CryptoPublicKey().(*rsa.PublicKey)
+	if !ok {
+		return 0, false
+	}
+	return rpub.N.BitLen(), true
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestPolicyMinRSABits(t *testing.T) {
+	weak, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := ssh.NewPublicKey(&weak.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, caKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := ssh.NewSignerFromKey(caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := &ssh.Certificate{
+		Key:             pub,
+		CertType:        ssh.UserCert,
+		ValidPrincipals: []string{"release"},
+		ValidBefore:     ssh.CertTimeInfinity,
+	}
+	if err := cert.SignCert(rand.Reader, ca); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, key := range map[string]ssh.PublicKey{
+		"key":         pub,
+		"certificate": cert,
+	} {
+		t.Run(name, func(t *testing.T) {
+			sig := &signature{
+				PublicKey:     key,
+				HashAlgorithm: "sha512",
+				Signature:     &ssh.Signature{Format: ssh.KeyAlgoRSASHA512},
+			}
+			if err := (policy{MinRSABits: 2048}).check(sig); !errors.Is(err, errKeyTooSmall) {
+				t.Errorf("expected a key too small error, got %v", err)
+			}
+			if err := (policy{MinRSABits: 1024}).check(sig); err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		})
+	}
+}
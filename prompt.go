@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"charm.land/huh/v2"
+	"github.com/charmbracelet/x/term"
+	"github.com/spf13/cobra"
+)
+
+// prompter is where interactive prompts read from and write to.
+type prompter struct {
+	In  io.Reader
+	Out io.Writer
+	// PassphraseCommand, when set, is run to get key passphrases instead of
+	// asking for them.
+	PassphraseCommand string
+}
+
+// newPrompter returns a prompter using the input and error output of the
+// given command, so they can be replaced with [cobra.Command.SetIn] and
+// [cobra.Command.SetErr].
+func newPrompter(cmd *cobra.Command) prompter {
+	return prompter{
+		In:  cmd.InOrStdin(),
+		Out: cmd.ErrOrStderr(),
+	}
+}
+
+// interactive reports whether prompts can be shown: the input is either a
+// terminal, or was injected.
+func (p prompter) interactive() bool {
+	f, ok := p.In.(*os.File)
+	return !ok || term.IsTerminal(f.Fd())
+}
+
+// run runs a single field, like [huh.Run].
+func (p prompter) run(field huh.Field) error {
+	return p.runGroups(huh.NewGroup(field))
+}
+
+// runGroups runs a form of the given groups.
+func (p prompter) runGroups(groups ...*huh.Group) error {
+	return huh.NewForm(groups...).
+		WithShowHelp(false).
+		WithInput(p.In).
+		WithOutput(p.Out).
+		Run()
+}
+
+// passphrase runs the passphrase command, e.g. "pass show ssh/key", through
+// the shell, and returns its output without the trailing newline. Its error
+// output goes to the prompt output, as it may need to ask for something too.
+func (p prompter) passphrase() ([]byte, error) {
+	var stdout bytes.Buffer
+	cmd := shellCommand(p.PassphraseCommand)
+	cmd.Stdout = &stdout
+	cmd.Stderr = p.Out
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("passphrase command %q: %w", p.PassphraseCommand, err)
+	}
+	pass := strings.TrimRight(stdout.String(), "\r\n")
+	if pass == "" {
+		return nil, fmt.Errorf("passphrase command %q printed nothing", p.PassphraseCommand)
+	}
+	return []byte(pass), nil
+}
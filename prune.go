@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// findOrphans lists the ".ssig" signatures under dir whose file doesn't
+// exist anymore. Signatures named by the content hash of their file, by
+// --by-content-hash-name, never have a file next to them, and are skipped.
+func findOrphans(dir string) ([]string, error) {
+	var orphans []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || !strings.HasSuffix(path, ".ssig") {
+			return nil
+		}
+		source := strings.TrimSuffix(path, ".ssig")
+		if isContentHash(filepath.Base(source)) {
+			return nil
+		}
+		// a dangling symlink is still there, it's not for prune to decide.
+		if _, err := os.Lstat(source); !errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		orphans = append(orphans, path)
+		return nil
+	})
+	return orphans, err
+}
+
+// isContentHash reports whether name is a hex encoded SHA256 digest.
+func isContentHash(name string) bool {
+	b, err := hex.DecodeString(name)
+	return err == nil && len(b) == 32 && name == strings.ToLower(name)
+}
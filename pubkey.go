@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// encodePublicKey encodes the key either as an authorized_keys line, or in
+// the SSH wire format, making sure it parses back to the same key.
+func encodePublicKey(pub ssh.PublicKey, format string) ([]byte, error) {
+	var out []byte
+	var back ssh.PublicKey
+	var err error
+	switch format {
+	case "authorized_keys":
+		out = ssh.MarshalAuthorizedKey(pub)
+		back, _, _, _, err = ssh.ParseAuthorizedKey(out)
+	case "wire":
+		out = pub.Marshal()
+		back, err = ssh.ParsePublicKey(out)
+	default:
+		return nil, fmt.Errorf("invalid format %q, expected authorized_keys or wire", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not parse converted key: %w", err)
+	}
+	if !bytes.Equal(back.Marshal(), pub.Marshal()) {
+		return nil, fmt.Errorf("converted key does not match the original")
+	}
+	return out, nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"golang.org/x/crypto/ssh"
+)
+
+var errWeakRandomness = errors.New("weak randomness")
+
+// systemRandom is [rand.Reader] as set by the standard library, the system
+// CSPRNG, before anything could replace it.
+var systemRandom = rand.Reader
+
+// checkRandomness fails if [rand.Reader] is not the system CSPRNG anymore,
+// e.g. when a test replaced it with a deterministic source.
+//
+// It only matters for keys whose signatures need randomness, see
+// [needsRandomness], as a predictable nonce can leak an ECDSA private key.
+func checkRandomness() error {
+	// the types are compared first, as comparing values of a type which is
+	// not comparable would panic.
+	if reflect.TypeOf(rand.Reader) != reflect.TypeOf(systemRandom) || rand.Reader != systemRandom {
+		return fmt.Errorf("%w: crypto/rand.Reader is not the system random number generator", errWeakRandomness)
+	}
+	return nil
+}
+
+// needsRandomness reports whether signing with the given key reads from
+// [rand.Reader]: Ed25519 signatures are deterministic, while ECDSA ones, and
+// RSA blinding, are not.
+func needsRandomness(pub ssh.PublicKey) bool {
+	switch keyAlgorithm(pub) {
+	case ssh.KeyAlgoED25519, ssh.KeyAlgoSKED25519:
+		return false
+	default:
+		return true
+	}
+}
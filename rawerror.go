@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/charmbracelet/fang"
+	"github.com/spf13/cobra"
+)
+
+// rawError adds the chain of wrapped errors to the message of an error, for
+// --show-raw-error.
+type rawError struct {
+	err error
+}
+
+func (e rawError) Error() string { return e.err.Error() + "\n\n" + e.chain() }
+func (e rawError) Unwrap() error { return e.err }
+
+func (e rawError) chain() string {
+	var b strings.Builder
+	b.WriteString("raw error chain:")
+	writeErrorChain(&b, e.err, 1)
+	return b.String()
+}
+
+// writeErrorChain writes err, with its type, and the errors it wraps, one
+// per line and indented by depth.
+func writeErrorChain(b *strings.Builder, err error, depth int) {
+	for err != nil {
+		fmt.Fprintf(b, "\n%s%T: %s", strings.Repeat("  ", depth), err, err)
+		if errs, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, err := range errs.Unwrap() {
+				writeErrorChain(b, err, depth+1)
+			}
+			return
+		}
+		err = errors.Unwrap(err)
+	}
+}
+
+// showRawErrors makes the commands under cmd return a [rawError] when show
+// is set by the time they run.
+func showRawErrors(cmd *cobra.Command, show *bool) {
+	if run := cmd.RunE; run != nil {
+		cmd.RunE = func(cmd *cobra.Command, args []string) error {
+			err := run(cmd, args)
+			if err != nil && *show {
+				return rawError{err}
+			}
+			return err
+		}
+	}
+	for _, c := range cmd.Commands() {
+		showRawErrors(c, show)
+	}
+}
+
+// silencedError is an error not to be printed, for "verify --silent".
+type silencedError struct {
+	err error
+}
+
+func (e silencedError) Error() string { return e.err.Error() }
+func (e silencedError) Unwrap() error { return e.err }
+
+// silence returns err as a [silencedError] if silent is set.
+func silence(err error, silent bool) error {
+	if err == nil || !silent {
+		return err
+	}
+	return silencedError{err}
+}
+
+func isSilenced(err error) bool {
+	var silenced silencedError
+	return errors.As(err, &silenced)
+}
+
+// fangErrorHandler prints errors like [fang.DefaultErrorHandler], with the
+// chain of a [rawError] after it, as fang's styling would join its lines,
+// and nothing for a [silencedError].
+func fangErrorHandler(w io.Writer, styles fang.Styles, err error) {
+	if isSilenced(err) {
+		return
+	}
+	var raw rawError
+	if !errors.As(err, &raw) {
+		fang.DefaultErrorHandler(w, styles, err)
+		return
+	}
+	fang.DefaultErrorHandler(w, styles, raw.err)
+	_, _ = fmt.Fprintln(w, raw.chain())
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	defaultReadBufferSize = 1 << 20
+	minReadBufferSize     = 4 << 10
+	maxReadBufferSize     = 64 << 20
+)
+
+// byteSize is the --read-buffer-size flag value, accepting sizes such as
+// 4096, 512KiB, or 4MiB, between 4KiB and 64MiB.
+type byteSize int
+
+var sizeUnits = []struct {
+	suffix string
+	size   int
+}{
+	{"GiB", 1 << 30},
+	{"MiB", 1 << 20},
+	{"KiB", 1 << 10},
+	{"B", 1},
+}
+
+func (b *byteSize) String() string {
+	for _, unit := range sizeUnits {
+		if int(*b) >= unit.size && int(*b)%unit.size == 0 {
+			return strconv.Itoa(int(*b)/unit.size) + unit.suffix
+		}
+	}
+	return strconv.Itoa(int(*b))
+}
+
+func (b *byteSize) Set(s string) error {
+	num, mult := s, 1
+	for _, unit := range sizeUnits {
+		if n, ok := strings.CutSuffix(s, unit.suffix); ok {
+			num, mult = n, unit.size
+			break
+		}
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(num))
+	if err != nil || n <= 0 {
+		return fmt.Errorf("invalid size %q", s)
+	}
+	if n > maxReadBufferSize/mult || n*mult < minReadBufferSize {
+		lo, hi := byteSize(minReadBufferSize), byteSize(maxReadBufferSize)
+		return fmt.Errorf("invalid size %q, expected between %s and %s", s, lo.String(), hi.String())
+	}
+	*b = byteSize(n * mult)
+	return nil
+}
+
+func (b *byteSize) Type() string {
+	return "size"
+}
+
+// readFile reads the named file in chunks of the given size, between 4KiB and
+// 64MiB as [byteSize.Set] ensures.
+func readFile(name string, size byteSize) ([]byte, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	if info, err := f.Stat(); err == nil && info.Mode().IsRegular() {
+		buf.Grow(int(info.Size()))
+	}
+	if _, err := io.Copy(&buf, bufio.NewReaderSize(f, int(size))); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// specialFileType returns what kind of special file name is, such as
+// "character device" for /dev/zero, or an empty string if it's a regular
+// file.
+func specialFileType(name string) (string, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return "", err
+	}
+	mode := info.Mode()
+	switch {
+	case mode.IsRegular():
+		return "", nil
+	case mode&fs.ModeCharDevice != 0:
+		return "character device", nil
+	case mode&fs.ModeDevice != 0:
+		return "block device", nil
+	case mode&fs.ModeNamedPipe != 0:
+		return "named pipe", nil
+	case mode&fs.ModeSocket != 0:
+		return "socket", nil
+	case mode.IsDir():
+		return "directory", nil
+	default:
+		return "irregular file", nil
+	}
+}
+
+// teeFile reads r like [readFile], while writing it to the named file, which
+// must not exist unless force is set. The file is removed if reading fails.
+func teeFile(r io.Reader, name string, force bool, size byteSize) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := copyToFile(r, name, force, size, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// copyToFile copies r to both w and the named file, like [teeFile].
+func copyToFile(r io.Reader, name string, force bool, size byteSize, w io.Writer) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(name, flags, 0o644)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(io.MultiWriter(f, w), bufio.NewReaderSize(r, int(size)))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(name)
+	}
+	return err
+}
+
+// writeNewFile writes data to the named file, which must not exist unless
+// force is set.
+func writeNewFile(name string, data []byte, force bool) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(name, flags, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// utf8BOM is the byte order mark some editors, mostly on Windows, put at the
+// start of UTF-8 text files.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// stripBOM removes a leading UTF-8 byte order mark from b, if any.
+func stripBOM(b []byte) []byte {
+	return bytes.TrimPrefix(b, utf8BOM)
+}
+
+// crlfNewlines converts the LF line endings of b to CRLF, leaving the ones
+// already in CRLF as they are.
+func crlfNewlines(b []byte) []byte {
+	var buf bytes.Buffer
+	buf.Grow(len(b) + bytes.Count(b, []byte("\n")))
+	for i, c := range b {
+		if c == '\n' && (i == 0 || b[i-1] != '\r') {
+			buf.WriteByte('\r')
+		}
+		buf.WriteByte(c)
+	}
+	return buf.Bytes()
+}
+
+// unicodeForms are the normalization forms of --normalize-unicode.
+var unicodeForms = map[string]norm.Form{
+	"nfc": norm.NFC,
+	"nfd": norm.NFD,
+}
+
+// checkUnicodeForm validates the --normalize-unicode form, empty meaning no
+// normalization.
+func checkUnicodeForm(form string) error {
+	if _, ok := unicodeForms[form]; !ok && form != "" {
+		return fmt.Errorf("invalid --normalize-unicode %q, expected nfc or nfd", form)
+	}
+	return nil
+}
+
+// normalizeUnicode converts the UTF-8 text b to the given normalization form,
+// so the same text signs the same whichever form it was written in.
+func normalizeUnicode(b []byte, form string) []byte {
+	if f, ok := unicodeForms[form]; ok {
+		return f.Bytes(b)
+	}
+	return b
+}
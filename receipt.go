@@ -0,0 +1,79 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+var errReceiptMismatch = errors.New("receipt mismatch")
+
+// receipt records what was signed, so the verification can be done again
+// from it with "ssign verify --receipt".
+type receipt struct {
+	File        string `json:"file"`
+	Signature   string `json:"signature"`
+	Fingerprint string `json:"fingerprint"`
+	Namespace   string `json:"namespace"`
+	// SHA256 is the digest of the file as it was read, before any
+	// transformation such as --strip-bom.
+	SHA256 string `json:"sha256"`
+}
+
+func newReceipt(res result, content []byte) receipt {
+	sum := sha256.Sum256(content)
+	return receipt{
+		File:        res.File,
+		Signature:   res.Signature,
+		Fingerprint: res.Fingerprint,
+		Namespace:   res.Namespace,
+		SHA256:      hex.EncodeToString(sum[:]),
+	}
+}
+
+func writeReceipt(name string, r receipt) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := printJSON(f, r); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func readReceipt(name string) (receipt, error) {
+	var r receipt
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return r, err
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return r, fmt.Errorf("%w: %w", errInvalidJSON, err)
+	}
+	if r.File == "" || r.Signature == "" || r.Fingerprint == "" || r.Namespace == "" || r.SHA256 == "" {
+		return r, fmt.Errorf("%w: file, signature, fingerprint, namespace, and sha256 are all required", errInvalidJSON)
+	}
+	return r, nil
+}
+
+// checkContent checks that content is still what was signed.
+func (r receipt) checkContent(content []byte) error {
+	sum := sha256.Sum256(content)
+	if got := hex.EncodeToString(sum[:]); got != r.SHA256 {
+		return fmt.Errorf("%w: %s has sha256 %s, the receipt recorded %s", errReceiptMismatch, r.File, got, r.SHA256)
+	}
+	return nil
+}
+
+// checkResult checks that the signature was verified with the recorded key.
+func (r receipt) checkResult(res result) error {
+	if res.Fingerprint != r.Fingerprint {
+		return fmt.Errorf("%w: %s was signed by %s, the receipt recorded %s", errReceiptMismatch, r.File, res.Fingerprint, r.Fingerprint)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+)
+
+// redacted returns the result without the paths it holds, so it can be
+// published: the file and signature paths are replaced by their SHA256, and
+// the key by its fingerprint (or the SHA256 of its name if the key is not
+// known). Those paths are replaced in the warning and error messages too,
+// where they appear as whole words. The fingerprint, namespace, outcome, and
+// error code are kept.
+func (r jsonResult) redacted() jsonResult {
+	paths := map[string]string{}
+	redact := func(path, with string) string {
+		if path == "" {
+			return path
+		}
+		paths[path] = with
+		return with
+	}
+
+	file, key := r.File, hashPath(r.Key)
+	if r.Fingerprint != "" {
+		key = r.Fingerprint
+	}
+	r.File = redact(r.File, hashPath(r.File))
+	r.Signature = redact(r.Signature, hashPath(r.Signature))
+	r.Key = redact(r.Key, key)
+	if r.Signature == "" && file != "" {
+		// the signature could not be read, its default path may be in the
+		// error.
+		redact(file+".ssig", hashPath(file+".ssig"))
+	}
+
+	// longer paths first, so "a.txt" is not replaced inside "a.txt.ssig".
+	olds := make([]string, 0, len(paths))
+	for path := range paths {
+		olds = append(olds, path)
+	}
+	sort.Slice(olds, func(i, j int) bool { return len(olds[i]) > len(olds[j]) })
+	for _, old := range olds {
+		r.Warning = replaceWord(r.Warning, old, paths[old])
+		r.Error = replaceWord(r.Error, old, paths[old])
+	}
+	return r
+}
+
+func hashPath(path string) string {
+	sum := sha256.Sum256([]byte(path))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// replaceWord replaces the occurrences of old in s which are not part of a
+// longer word, e.g. a path in an error message, but not in another path.
+func replaceWord(s, old, with string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, old)
+		if i < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		end := i + len(old)
+		if wordBoundary(s[:i], true) && wordBoundary(s[end:], false) {
+			b.WriteString(s[:i] + with)
+		} else {
+			b.WriteString(s[:end])
+		}
+		s = s[end:]
+	}
+}
+
+// wordBoundary reports whether a path can end before rest, or start after
+// it when before is set: at the start or end of the text, or next to a
+// space, a quote, or punctuation ending a clause.
+func wordBoundary(s string, before bool) bool {
+	if s == "" {
+		return true
+	}
+	if before {
+		return strings.ContainsAny(s[len(s)-1:], " \t\n\"'(")
+	}
+	if s[0] == '.' {
+		// the end of a sentence, not an extension.
+		return len(s) == 1 || s[1] == ' '
+	}
+	return strings.ContainsAny(s[:1], " \t\n\"'):,")
+}
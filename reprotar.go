@@ -0,0 +1,116 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// reproducibleTar returns what --reproducible-tar signs for a directory: a
+// tar stream of its content that only depends on the names, the content, the
+// executable bits, and the symlink targets of its entries, normalized as:
+//
+//   - entries are named by their path relative to the directory, with "/"
+//     separators and a trailing "/" for directories, the directory itself
+//     not being an entry;
+//   - entries are sorted by name within each directory, each directory being
+//     followed by its own entries, as with GNU tar --sort=name;
+//   - regular files have mode 0755 if any of their executable bits is set,
+//     0644 otherwise, directories 0755, and symlinks 0777, storing their
+//     target as is, without following them;
+//   - hard links are stored as regular files;
+//   - the modification time is the Unix epoch, the owner and group are 0
+//     with no names, and no other metadata, e.g. extended attributes, is
+//     stored;
+//   - headers are in the PAX format, with extended records only for what
+//     USTAR can't hold, such as long names, and the stream ends with the two
+//     zero blocks of the tar format, without compression;
+//   - other file types, e.g. devices or named pipes, are refused.
+//
+// This is the same as, e.g., "tar --sort=name --mtime=@0 --owner=0
+// --group=0 --numeric-owner --format=pax", with the modes normalized, but
+// only the stream this function generates is guaranteed to verify.
+func reproducibleTar(dir string) ([]byte, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dir {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		hdr := &tar.Header{
+			Name:    filepath.ToSlash(rel),
+			ModTime: time.Unix(0, 0),
+			Format:  tar.FormatPAX,
+		}
+		switch typ := d.Type(); {
+		case typ.IsDir():
+			hdr.Typeflag = tar.TypeDir
+			hdr.Name += "/"
+			hdr.Mode = 0o755
+			return tw.WriteHeader(hdr)
+		case typ&fs.ModeSymlink != 0:
+			hdr.Typeflag = tar.TypeSymlink
+			hdr.Mode = 0o777
+			hdr.Linkname, err = os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return tw.WriteHeader(hdr)
+		case typ.IsRegular():
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			hdr.Typeflag = tar.TypeReg
+			hdr.Mode = 0o644
+			if info.Mode()&0o111 != 0 {
+				hdr.Mode = 0o755
+			}
+			hdr.Size = info.Size()
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			return copyFileTo(tw, path)
+		default:
+			return fmt.Errorf("cannot add %s to a reproducible tar, it's not a regular file, a directory, or a symlink", path)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// copyFileTo copies the named file to w.
+func copyFileTo(w io.Writer, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// signState records the files of a directory already signed, one per line,
+// so an interrupted "sign --continue-from" picks up where it left off.
+//
+// Each line has the fingerprint of the key and the namespace the file was
+// signed with, and the file, separated by tabs, so resuming with another key
+// or namespace signs the files again.
+type signState struct {
+	f           *os.File
+	fingerprint string
+	namespace   string
+	done        map[string]bool
+}
+
+// openSignState reads the files recorded in the given state file as signed
+// by the key with the given fingerprint in the namespace, creating it if it
+// doesn't exist, and opens it to record more.
+func openSignState(name, fingerprint, namespace string) (*signState, error) {
+	done := map[string]bool{}
+	in, err := os.Open(name)
+	switch {
+	case err == nil:
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			// lines of another key or namespace, or without them, as
+			// written by older versions, are ignored.
+			fields := strings.SplitN(scanner.Text(), "\t", 3)
+			if len(fields) == 3 && fields[0] == fingerprint && fields[1] == namespace {
+				done[fields[2]] = true
+			}
+		}
+		err = scanner.Err()
+		in.Close()
+		if err != nil {
+			return nil, err
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, err
+	}
+
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &signState{f: f, fingerprint: fingerprint, namespace: namespace, done: done}, nil
+}
+
+// add records the file as signed. It's written right away, so it survives
+// the process being interrupted.
+func (s *signState) add(file string) error {
+	s.done[file] = true
+	_, err := fmt.Fprintf(s.f, "%s\t%s\t%s\n", s.fingerprint, s.namespace, file)
+	return err
+}
+
+func (s *signState) Close() error {
+	return s.f.Close()
+}
+
+// resumeCounts are the results of a resumed directory signing.
+type resumeCounts struct {
+	// Signed is how many files were signed.
+	Signed int
+	// Resumed is how many were skipped, as they were already signed.
+	Resumed int
+}
+
+// signDir signs each of the given files to its ".ssig" signature, skipping
+// the ones already signed: those recorded in the state whose signature is
+// still newer than the file, and the ones whose up-to-date signature is
+// valid for the key, which are then recorded too.
+func signDir(files []string, state *signState, key ssh.Signer, namespace string, wrap int, size byteSize) (resumeCounts, error) {
+	var counts resumeCounts
+	for _, file := range files {
+		sigName := file + ".ssig"
+		if upToDate(file, sigName) && (state.done[file] || signedBy(file, sigName, key.PublicKey(), namespace, size)) {
+			if !state.done[file] {
+				if err := state.add(file); err != nil {
+					return counts, fmt.Errorf("could not record %s: %w", file, err)
+				}
+			}
+			counts.Resumed++
+			continue
+		}
+
+		message, err := readFile(file, size)
+		if err != nil {
+			return counts, fmt.Errorf("could not open file %s: %w", file, err)
+		}
+		data, err := signMessage(key, message, namespace)
+		if err == nil && wrap != defaultPEMWrap {
+			data, err = wrapPEM(data, wrap)
+		}
+		if err != nil {
+			return counts, fmt.Errorf("%s: %w", file, err)
+		}
+		if err := os.WriteFile(sigName, data, 0o644); err != nil {
+			return counts, fmt.Errorf("could not write signature %s: %w", sigName, err)
+		}
+		if err := state.add(file); err != nil {
+			return counts, fmt.Errorf("could not record %s: %w", file, err)
+		}
+		counts.Signed++
+	}
+	return counts, nil
+}
+
+// upToDate reports whether the signature exists and was not modified before
+// the file.
+func upToDate(file, sigName string) bool {
+	sigInfo, err := os.Stat(sigName)
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return false
+	}
+	return !sigInfo.ModTime().Before(info.ModTime())
+}
+
+// signedBy reports whether the signature is a valid signature of the file by
+// the given key.
+func signedBy(file, sigName string, pub ssh.PublicKey, namespace string, size byteSize) bool {
+	signature, err := os.ReadFile(sigName)
+	if err != nil {
+		return false
+	}
+	message, err := readFile(file, size)
+	if err != nil {
+		return false
+	}
+	return verifyMessage(pub, message, signature, namespace) == nil
+}
+
+// filesToSign lists the files under dir to sign with --continue-from,
+// leaving out the signatures and the state file itself.
+func filesToSign(dir, stateName string) ([]string, error) {
+	files, err := findRequired(dir, []string{"*"})
+	if err != nil {
+		return nil, err
+	}
+	state, err := filepath.Abs(stateName)
+	if err != nil {
+		return nil, err
+	}
+	out := files[:0]
+	for _, file := range files {
+		if abs, err := filepath.Abs(file); err == nil && abs == state {
+			continue
+		}
+		out = append(out, file)
+	}
+	return out, nil
+}
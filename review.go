@@ -0,0 +1,43 @@
+package main
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+
+	"charm.land/huh/v2"
+	"golang.org/x/crypto/ssh"
+)
+
+var errNotConfirmed = errors.New("signature not confirmed")
+
+// printSignatureReview prints what a signature is about to be written for,
+// for --two-phase: the SHA256 of the file, the key, the namespace, and the
+// signature itself.
+func printSignatureReview(w io.Writer, subject string, content []byte, pub ssh.PublicKey, namespace string, signature []byte) {
+	fmt.Fprintf(w, "File:        %s\n", subject)
+	fmt.Fprintf(w, "SHA256:      %x\n", sha256.Sum256(content))
+	fmt.Fprintf(w, "Key:         %s %s\n", pub.Type(), ssh.FingerprintSHA256(pub))
+	fmt.Fprintf(w, "Namespace:   %s\n", namespace)
+	fmt.Fprintf(w, "\n%s\n", signature)
+}
+
+// confirmSignature asks whether to write the signature just reviewed.
+func confirmSignature(p prompter, subject string) error {
+	if !p.interactive() {
+		return fmt.Errorf("%w: confirming it needs a terminal, use --yes to write it anyway", errNotConfirmed)
+	}
+	var ok bool
+	if err := p.run(
+		huh.NewConfirm().
+			Title(fmt.Sprintf("Write the signature of %s?", subject)).
+			Value(&ok),
+	); err != nil {
+		return fmt.Errorf("could not confirm signature: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("%w: it was not written", errNotConfirmed)
+	}
+	return nil
+}
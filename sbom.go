@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	errInvalidSBOM  = errors.New("invalid SBOM")
+	errSBOMMismatch = errors.New("SBOM signer mismatch")
+)
+
+// The CycloneDX properties declaring who signs a component.
+const (
+	sbomFingerprintProperty = "ssign:fingerprint"
+	sbomPublicKeyProperty   = "ssign:public-key"
+)
+
+// cycloneDXComponent is the part of a CycloneDX component ssign reads.
+type cycloneDXComponent struct {
+	Name       string `json:"name"`
+	Properties []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"properties"`
+	Components []cycloneDXComponent `json:"components"`
+}
+
+// sbomSigner is the signer a SBOM declares for a component: its public key,
+// its fingerprint, or both.
+type sbomSigner struct {
+	Component   string
+	PublicKey   ssh.PublicKey
+	Fingerprint string
+}
+
+// readSBOMSigner finds the given component in a CycloneDX JSON SBOM, in its
+// metadata or anywhere in its components, and reads the signer declared in
+// its "ssign:public-key" (in the authorized_keys format) and
+// "ssign:fingerprint" properties.
+func readSBOMSigner(name, component string) (sbomSigner, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return sbomSigner{}, err
+	}
+	var bom struct {
+		BOMFormat string `json:"bomFormat"`
+		Metadata  struct {
+			Component *cycloneDXComponent `json:"component"`
+		} `json:"metadata"`
+		Components []cycloneDXComponent `json:"components"`
+	}
+	if err := json.Unmarshal(data, &bom); err != nil {
+		return sbomSigner{}, fmt.Errorf("%w: %w", errInvalidJSON, err)
+	}
+	if bom.BOMFormat != "CycloneDX" {
+		return sbomSigner{}, fmt.Errorf("%w: bomFormat is %q, only CycloneDX is supported", errInvalidSBOM, bom.BOMFormat)
+	}
+
+	components := bom.Components
+	if bom.Metadata.Component != nil {
+		components = append([]cycloneDXComponent{*bom.Metadata.Component}, components...)
+	}
+	c, ok := findComponent(components, component)
+	if !ok {
+		return sbomSigner{}, fmt.Errorf("%w: no component %q", errInvalidSBOM, component)
+	}
+
+	signer := sbomSigner{Component: component}
+	for _, p := range c.Properties {
+		switch p.Name {
+		case sbomFingerprintProperty:
+			signer.Fingerprint = p.Value
+		case sbomPublicKeyProperty:
+			signer.PublicKey, _, _, _, err = ssh.ParseAuthorizedKey([]byte(p.Value))
+			if err != nil {
+				return sbomSigner{}, fmt.Errorf("%w: %s of component %q: %w", errInvalidSBOM, sbomPublicKeyProperty, component, err)
+			}
+		}
+	}
+	switch {
+	case signer.PublicKey == nil && signer.Fingerprint == "":
+		return sbomSigner{}, fmt.Errorf("%w: component %q declares no %s or %s property", errInvalidSBOM, component, sbomPublicKeyProperty, sbomFingerprintProperty)
+	case signer.PublicKey != nil && signer.Fingerprint == "":
+		signer.Fingerprint = ssh.FingerprintSHA256(signer.PublicKey)
+	case signer.PublicKey != nil && ssh.FingerprintSHA256(signer.PublicKey) != signer.Fingerprint:
+		return sbomSigner{}, fmt.Errorf("%w: component %q declares key %s, but fingerprint %s", errInvalidSBOM, component, ssh.FingerprintSHA256(signer.PublicKey), signer.Fingerprint)
+	}
+	return signer, nil
+}
+
+// findComponent finds the first component with the given name, depth first.
+func findComponent(components []cycloneDXComponent, name string) (cycloneDXComponent, bool) {
+	for _, c := range components {
+		if c.Name == name {
+			return c, true
+		}
+		if found, ok := findComponent(c.Components, name); ok {
+			return found, true
+		}
+	}
+	return cycloneDXComponent{}, false
+}
+
+// checkResult checks that the signature was made by the declared signer.
+func (s sbomSigner) checkResult(res result) error {
+	if res.Fingerprint != s.Fingerprint {
+		return fmt.Errorf("%w: %s was signed by %s, the SBOM declares %s for component %q", errSBOMMismatch, res.File, res.Fingerprint, s.Fingerprint, s.Component)
+	}
+	return nil
+}
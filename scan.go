@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var errNotSigned = errors.New("not signed")
+
+// findRequired lists the files under dir matching any of the patterns, which
+// are matched against the file name, or against the slash separated path
+// relative to dir when they contain a slash.
+func findRequired(dir string, patterns []string) ([]string, error) {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	var files []string
+	err := filepath.WalkDir(dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || strings.HasSuffix(name, ".ssig") {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, name)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		for _, pattern := range patterns {
+			if matchPattern(pattern, rel) {
+				files = append(files, name)
+				return nil
+			}
+		}
+		return nil
+	})
+	return files, err
+}
+
+// matchPattern matches the pattern against the base name of the slash
+// separated path rel, or against rel itself when the pattern has a slash.
+func matchPattern(pattern, rel string) bool {
+	subject := path.Base(rel)
+	if strings.Contains(pattern, "/") {
+		subject = rel
+	}
+	ok, _ := path.Match(pattern, subject)
+	return ok
+}
+
+// scan verifies each of the given files against its ".ssig" signature, which
+// fails the files without one.
+func (v verifier) scan(files []string, readBufferSize byteSize) []batchResult {
+	results := make([]batchResult, 0, len(files))
+	for _, file := range files {
+		if !exists(file + ".ssig") {
+			results = append(results, batchResult{
+				result: result{File: file, Key: v.KeyName, Namespace: v.Namespace},
+				Err:    fmt.Errorf("%w: %s is missing", errNotSigned, file+".ssig"),
+			})
+			continue
+		}
+		results = append(results, v.verifyBatch([]string{file}, false, readBufferSize)...)
+	}
+	return results
+}
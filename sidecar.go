@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+var (
+	errInvalidSidecar  = errors.New("invalid sidecar")
+	errSidecarMismatch = errors.New("sidecar mismatch")
+)
+
+// sidecarSuffix is appended to a file name to find its sidecar.
+const sidecarSuffix = ".meta.json"
+
+// sidecar is a file's "<file>.meta.json" metadata, locating its signature
+// when it isn't named by convention, as read by "ssign verify --use-sidecar".
+type sidecar struct {
+	// Signature is the path of the signature, relative to the sidecar.
+	Signature   string `json:"signature"`
+	Fingerprint string `json:"fingerprint"`
+	Namespace   string `json:"namespace"`
+}
+
+// readSidecar reads the sidecar of the given file, rejecting unknown and
+// missing fields.
+func readSidecar(file string) (sidecar, error) {
+	var s sidecar
+	data, err := os.ReadFile(file + sidecarSuffix)
+	if err != nil {
+		return s, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&s); err != nil {
+		return s, fmt.Errorf("%w: %w", errInvalidSidecar, err)
+	}
+	if dec.More() {
+		return s, fmt.Errorf("%w: unexpected data after the object", errInvalidSidecar)
+	}
+	if s.Signature == "" || s.Fingerprint == "" || s.Namespace == "" {
+		return s, fmt.Errorf("%w: signature, fingerprint, and namespace are all required", errInvalidSidecar)
+	}
+	if !filepath.IsAbs(s.Signature) {
+		s.Signature = filepath.Join(filepath.Dir(file), s.Signature)
+	}
+	return s, nil
+}
+
+// checkResult checks that the signature was verified with the key the
+// sidecar names.
+func (s sidecar) checkResult(res result) error {
+	if res.Fingerprint != s.Fingerprint {
+		return fmt.Errorf("%w: %s was signed by %s, the sidecar names %s", errSidecarMismatch, res.File, res.Fingerprint, s.Fingerprint)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// sigDBBucket is the bolt bucket holding the signatures, by id.
+const sigDBBucket = "signatures"
+
+// readDBSignature reads the signature stored under id in the "signatures"
+// bucket of a bolt database, as used by applications keeping their
+// signatures in an embedded database instead of files.
+func readDBSignature(name, id string) ([]byte, error) {
+	db, err := bolt.Open(name, 0o600, &bolt.Options{
+		ReadOnly: true,
+		// the application owning the database may hold its lock.
+		Timeout: time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var signature []byte
+	err = db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(sigDBBucket))
+		if bucket == nil {
+			return fmt.Errorf("%w: no %q bucket", errNotSigned, sigDBBucket)
+		}
+		value := bucket.Get([]byte(id))
+		if value == nil {
+			return fmt.Errorf("%w: no signature with id %q", errNotSigned, id)
+		}
+		// the value is only valid during the transaction.
+		signature = append([]byte(nil), value...)
+		return nil
+	})
+	return signature, err
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// signature is a decoded SSHSIG signature.
+type signature struct {
+	PublicKey     ssh.PublicKey
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Signature     *ssh.Signature
+}
+
+// signedData according to the SSHSIG protocol.
+type signedData struct {
+	MagicPreamble [6]byte
+	Version       uint32
+	PublicKey     []byte
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Signature     []byte
+}
+
+const signaturePEMType = "SSH SIGNATURE"
+
+var (
+	errInvalidSignature   = errors.New("invalid PEM signature")
+	errMalformedSignature = errors.New("malformed signature")
+)
+
+// decodeSignature returns the raw SSHSIG blob from a PEM encoded signature,
+// or from a signature encoded as a single base64 line.
+func decodeSignature(in []byte) ([]byte, error) {
+	block, _ := pem.Decode(in)
+	if block != nil {
+		if block.Type != signaturePEMType {
+			return nil, fmt.Errorf("%w: unexpected type %s", errInvalidSignature, block.Type)
+		}
+		return block.Bytes, nil
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(in)))
+	if err != nil || !bytes.HasPrefix(raw, []byte("SSHSIG")) {
+		return nil, errInvalidSignature
+	}
+	return raw, nil
+}
+
+// encodeSignature encodes a raw SSHSIG blob in the given format, either "pem"
+// or "base64", keeping the given PEM headers.
+func encodeSignature(raw []byte, format string, headers map[string]string) ([]byte, error) {
+	switch format {
+	case "pem":
+		return pem.EncodeToMemory(&pem.Block{
+			Type:    signaturePEMType,
+			Headers: headers,
+			Bytes:   raw,
+		}), nil
+	case "base64":
+		if len(headers) > 0 {
+			return nil, fmt.Errorf("signatures with PEM headers cannot be encoded as base64")
+		}
+		return []byte(base64.StdEncoding.EncodeToString(raw) + "\n"), nil
+	default:
+		return nil, fmt.Errorf("invalid format %q, expected pem or base64", format)
+	}
+}
+
+// defaultPEMWrap is the width of the base64 lines of PEM signatures, as
+// written by encoding/pem and OpenSSH.
+const defaultPEMWrap = 64
+
+// wrapPEM re-encodes a PEM signature, keeping its headers, with the base64
+// lines wrapped at width characters instead of 64.
+func wrapPEM(sig []byte, width int) ([]byte, error) {
+	block, _ := pem.Decode(sig)
+	if block == nil {
+		return nil, errInvalidSignature
+	}
+	armor := pem.EncodeToMemory(&pem.Block{Type: block.Type, Headers: block.Headers})
+	end := bytes.LastIndex(armor, []byte("-----END "))
+
+	var out bytes.Buffer
+	out.Write(armor[:end])
+	body := base64.StdEncoding.EncodeToString(block.Bytes)
+	for len(body) > 0 {
+		n := min(width, len(body))
+		out.WriteString(body[:n] + "\n")
+		body = body[n:]
+	}
+	out.Write(armor[end:])
+	return out.Bytes(), nil
+}
+
+// parseSignature decodes a SSHSIG signature, without verifying it.
+func parseSignature(in []byte) (*signature, error) {
+	raw, err := decodeSignature(in)
+	if err != nil {
+		return nil, err
+	}
+
+	var data signedData
+	if err := ssh.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("%w: %w", errMalformedSignature, err)
+	}
+	if s := string(data.MagicPreamble[:]); s != "SSHSIG" {
+		return nil, fmt.Errorf("%w: invalid header %s", errMalformedSignature, s)
+	}
+	if data.Version != 1 {
+		return nil, fmt.Errorf("%w: invalid version %d", errMalformedSignature, data.Version)
+	}
+
+	pub, err := ssh.ParsePublicKey(data.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid public key: %w", errMalformedSignature, err)
+	}
+
+	var sig ssh.Signature
+	if err := ssh.Unmarshal(data.Signature, &sig); err != nil {
+		return nil, fmt.Errorf("%w: %w", errMalformedSignature, err)
+	}
+
+	return &signature{
+		PublicKey:     pub,
+		Namespace:     data.Namespace,
+		Reserved:      data.Reserved,
+		HashAlgorithm: data.HashAlgorithm,
+		Signature:     &sig,
+	}, nil
+}
+
+// signatureInfo holds the details of a signature, as shown by inspect.
+type signatureInfo struct {
+	Signature          string `json:"signature" yaml:"signature"`
+	KeyType            string `json:"key_type" yaml:"key_type"`
+	KeyFingerprint     string `json:"key_fingerprint" yaml:"key_fingerprint"`
+	Namespace          string `json:"namespace" yaml:"namespace"`
+	HashAlgorithm      string `json:"hash_algorithm" yaml:"hash_algorithm"`
+	SignatureAlgorithm string `json:"signature_algorithm" yaml:"signature_algorithm"`
+	Reserved           string `json:"reserved,omitempty" yaml:"reserved,omitempty"`
+}
+
+func newSignatureInfo(name string, sig *signature) signatureInfo {
+	return signatureInfo{
+		Signature:          name,
+		KeyType:            sig.PublicKey.Type(),
+		KeyFingerprint:     ssh.FingerprintSHA256(sig.PublicKey),
+		Namespace:          sig.Namespace,
+		HashAlgorithm:      sig.HashAlgorithm,
+		SignatureAlgorithm: sig.Signature.Format,
+		Reserved:           sig.Reserved,
+	}
+}
+
+func (i signatureInfo) lines() [][2]string {
+	lines := [][2]string{
+		{"Signature", i.Signature},
+		{"Key type", i.KeyType},
+		{"Key fingerprint", i.KeyFingerprint},
+		{"Namespace", i.Namespace},
+		{"Hash algorithm", i.HashAlgorithm},
+		{"Signature algorithm", i.SignatureAlgorithm},
+	}
+	if i.Reserved != "" {
+		// quoted, as it may hold any bytes.
+		lines = append(lines, [2]string{"Reserved", strconv.Quote(i.Reserved)})
+	}
+	return lines
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// signedBlob is the data SSHSIG signs: the hash of the message, and the
+// context it was signed in.
+type signedBlob struct {
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Hash          []byte
+}
+
+// teeDigest copies r to the named file like [teeFile], returning the SHA512
+// digest of the copied bytes instead of keeping them in memory.
+func teeDigest(r io.Reader, name string, force bool, size byteSize) ([]byte, error) {
+	h := sha512.New()
+	if err := copyToFile(r, name, force, size, h); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+var errCommandFailed = errors.New("command failed")
+
+// commandDigest runs the command through the shell, returning the SHA512
+// digest of its output, hashed as it's read like [teeDigest] does. The output
+// is also copied to the tee file, if any, which is removed if the command
+// exits with a non-zero status.
+func commandDigest(command string, stdin io.Reader, stderr io.Writer, tee string, force bool, size byteSize) ([]byte, error) {
+	cmd := shellCommand(command)
+	cmd.Stdin = stdin
+	cmd.Stderr = stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("%w: %q: %w", errCommandFailed, command, err)
+	}
+
+	h := sha512.New()
+	if tee != "" {
+		err = copyToFile(stdout, tee, force, size, h)
+	} else {
+		n := min(max(int(size), minReadBufferSize), maxReadBufferSize)
+		_, err = io.Copy(h, bufio.NewReaderSize(stdout, n))
+	}
+	if err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return nil, err
+	}
+	if err := cmd.Wait(); err != nil {
+		if tee != "" {
+			_ = os.Remove(tee)
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("%w: %q exited with status %d", errCommandFailed, command, exitErr.ExitCode())
+		}
+		return nil, fmt.Errorf("%w: %q: %w", errCommandFailed, command, err)
+	}
+	return h.Sum(nil), nil
+}
+
+// signDigest signs a message given its SHA512 digest, producing the same
+// signature as [sshsig.Sign] does with the message itself, so large messages
+// can be hashed while they are streamed.
+func signDigest(key ssh.Signer, digest []byte, namespace string) ([]byte, error) {
+	signer, ok := key.(ssh.AlgorithmSigner)
+	if !ok {
+		return nil, fmt.Errorf("cannot use this key")
+	}
+	algorithm := ssh.KeyAlgoRSASHA512
+	if multi, ok := key.(ssh.MultiAlgorithmSigner); ok {
+		algorithm = multi.Algorithms()[0]
+	}
+
+	blob := append([]byte("SSHSIG"), ssh.Marshal(signedBlob{
+		Namespace:     namespace,
+		HashAlgorithm: "sha512",
+		Hash:          digest,
+	})...)
+	sig, err := signer.SignWithAlgorithm(rand.Reader, blob, algorithm)
+	if isAgentRefusal(err) {
+		return nil, fmt.Errorf("could not sign: %w", errKeyLocked)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not sign: %w", err)
+	}
+
+	data := signedData{
+		Version:       1,
+		PublicKey:     key.PublicKey().Marshal(),
+		Namespace:     namespace,
+		HashAlgorithm: "sha512",
+		Signature:     ssh.Marshal(sig),
+	}
+	copy(data.MagicPreamble[:], "SSHSIG")
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  signaturePEMType,
+		Bytes: ssh.Marshal(data),
+	}), nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// result holds the fields available to --template.
+type result struct {
+	File        string `json:"file,omitempty"`
+	Signature   string `json:"signature,omitempty"`
+	Key         string `json:"key,omitempty"`
+	KeyID       string `json:"key_id,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	Namespace   string `json:"namespace,omitempty"`
+}
+
+const templateFields = ".File, .Signature, .Key, .KeyID, .Fingerprint, .Namespace"
+
+// printTemplate renders the given result with tmpl into w, ending it with a
+// newline.
+func printTemplate(w io.Writer, tmpl string, r result) error {
+	t, err := template.New("output").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("invalid template: %w", err)
+	}
+
+	var sb strings.Builder
+	if err := t.Execute(&sb, r); err != nil {
+		return fmt.Errorf("invalid template: %w", err)
+	}
+	if !strings.HasSuffix(sb.String(), "\n") {
+		sb.WriteString("\n")
+	}
+	_, err = io.WriteString(w, sb.String())
+	return err
+}
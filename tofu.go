@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"charm.land/huh/v2"
+	"golang.org/x/crypto/ssh"
+)
+
+var errUntrustedKey = errors.New("key not trusted")
+
+// knownKeysFile returns the path of the keys trusted on first use, in the
+// authorized_keys format.
+func knownKeysFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "ssign", "known_keys"), nil
+}
+
+// isKnownKey reports whether the given key was trusted before.
+func isKnownKey(name string, pub ssh.PublicKey) (bool, error) {
+	known, err := openPublicKeys(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	fp := ssh.FingerprintSHA256(pub)
+	for _, k := range known {
+		if ssh.FingerprintSHA256(k) == fp {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// rememberKey adds the given key to the known keys.
+func rememberKey(name string, pub ssh.PublicKey) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(ssh.MarshalAuthorizedKey(pub)); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// confirmKey makes sure the user trusts the given key, either because it's a
+// known key, because of trust, or by asking them, like SSH does when
+// connecting to a host for the first time. Accepted keys are remembered.
+func confirmKey(p prompter, pub ssh.PublicKey, trust bool) error {
+	name, err := knownKeysFile()
+	if err != nil {
+		return fmt.Errorf("could not find known keys: %w", err)
+	}
+	known, err := isKnownKey(name, pub)
+	if err != nil {
+		return fmt.Errorf("could not read known keys %s: %w", name, err)
+	}
+	if known || trust {
+		return nil
+	}
+
+	fp := ssh.FingerprintSHA256(pub)
+	if !p.interactive() {
+		return fmt.Errorf("%w: %s is not a known key, confirm it on a terminal or use --trust-embedded-key", errUntrustedKey, fp)
+	}
+	var ok bool
+	if err := p.run(
+		huh.NewConfirm().
+			Title(fmt.Sprintf("The signature was made with the %s key %s, which is not known.", pub.Type(), fp)).
+			Description("Do you trust it?").
+			Value(&ok),
+	); err != nil {
+		return fmt.Errorf("could not confirm key: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s was rejected", errUntrustedKey, fp)
+	}
+	if err := rememberKey(name, pub); err != nil {
+		return fmt.Errorf("could not remember key in %s: %w", name, err)
+	}
+	return nil
+}
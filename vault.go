@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	errVaultUnsupported = errors.New("ssign was built without Vault support")
+	errInvalidVaultKey  = errors.New("invalid Vault key")
+)
+
+// vaultKeyField is the field of the Vault secret holding the trusted public
+// keys, in the authorized_keys format, one per line so keys can be rotated.
+const vaultKeyField = "public_key"
+
+// openVaultPublicKeys reads the trusted public keys from the secret at path
+// in HashiCorp Vault, configured and authenticated by the standard VAULT_*
+// environment variables.
+//
+// The keys are cached for ttl, if positive, in the ssign/vault directory of
+// the user cache directory, so verifying many files doesn't hit Vault each
+// time. Rotated keys are then only picked up once the cache expires.
+func openVaultPublicKeys(ctx context.Context, path string, ttl time.Duration) ([]ssh.PublicKey, error) {
+	cache, err := vaultCacheFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(cache); ttl > 0 && err == nil && time.Since(info.ModTime()) < ttl {
+		if pubs, err := openPublicKeys(cache); err == nil {
+			return pubs, nil
+		}
+	}
+
+	data, err := readVaultSecret(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := data[vaultKeyField].(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: the secret has no %q field", errInvalidVaultKey, vaultKeyField)
+	}
+	pubs, err := parseAuthorizedKeys([]byte(value))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidVaultKey, err)
+	}
+	if ttl > 0 {
+		// the keys were fetched, failing to cache them only makes the next
+		// verification fetch them again.
+		if err := os.MkdirAll(filepath.Dir(cache), 0o700); err == nil {
+			_ = os.WriteFile(cache, []byte(value), 0o600)
+		}
+	}
+	return pubs, nil
+}
+
+// vaultCacheFile returns where the keys of the given secret are cached,
+// named after the Vault address, namespace, and path of the secret.
+func vaultCacheFile(path string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(os.Getenv("VAULT_ADDR") + "\x00" + os.Getenv("VAULT_NAMESPACE") + "\x00" + path))
+	return filepath.Join(dir, "ssign", "vault", hex.EncodeToString(sum[:])), nil
+}
+
+// parseAuthorizedKeys parses all the keys in the authorized_keys format,
+// requiring at least one.
+func parseAuthorizedKeys(in []byte) ([]ssh.PublicKey, error) {
+	var pubs []ssh.PublicKey
+	for rest := in; len(rest) > 0; {
+		pub, _, _, r, err := ssh.ParseAuthorizedKey(rest)
+		if err != nil {
+			if len(pubs) > 0 {
+				break
+			}
+			return nil, err
+		}
+		pubs, rest = append(pubs, pub), r
+	}
+	if len(pubs) == 0 {
+		return nil, errors.New("no key found")
+	}
+	return pubs, nil
+}
@@ -0,0 +1,56 @@
+//go:build vault
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	vault "github.com/hashicorp/vault/api"
+)
+
+// readVaultSecret reads the data of the secret at path, handling the version
+// 2 key/value stores as "vault kv get" does: "secret/ssign/pubkey" is read
+// from "secret/data/ssign/pubkey" if "secret" is such a store.
+func readVaultSecret(ctx context.Context, path string) (map[string]any, error) {
+	// the address, token, namespace, and TLS settings are read from the
+	// VAULT_* environment variables.
+	client, err := vault.NewClient(vault.DefaultConfig())
+	if err != nil {
+		return nil, err
+	}
+	path = strings.Trim(path, "/")
+
+	if mount, ok := kvV2Mount(ctx, client, path); ok {
+		secret, err := client.KVv2(mount).Get(ctx, strings.TrimPrefix(path, mount))
+		if err != nil {
+			return nil, err
+		}
+		return secret.Data, nil
+	}
+
+	secret, err := client.Logical().ReadWithContext(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, fmt.Errorf("no secret at %s", path)
+	}
+	return secret.Data, nil
+}
+
+// kvV2Mount returns the mount of the version 2 key/value store path is in, if
+// any. Tokens not allowed to know it read the path as is.
+func kvV2Mount(ctx context.Context, client *vault.Client, path string) (string, bool) {
+	secret, err := client.Logical().ReadWithContext(ctx, "sys/internal/ui/mounts/"+path)
+	if err != nil || secret == nil {
+		return "", false
+	}
+	mount, _ := secret.Data["path"].(string)
+	options, _ := secret.Data["options"].(map[string]any)
+	if mount == "" || options["version"] != "2" {
+		return "", false
+	}
+	return mount, true
+}
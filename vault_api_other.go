@@ -0,0 +1,12 @@
+//go:build !vault
+
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+func readVaultSecret(context.Context, string) (map[string]any, error) {
+	return nil, fmt.Errorf("%w, build it with -tags vault", errVaultUnsupported)
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// verifier holds the options used to verify signatures.
+type verifier struct {
+	// Pubs are the trusted keys, when empty the key embedded in the signature
+	// is used instead.
+	Pubs          []ssh.PublicKey
+	KeyName       string
+	Namespace     string
+	Policy        policy
+	StripBOM      bool
+	CRLF          bool
+	JSONCanonical bool
+	JCS           bool
+	JSONPointer   string
+	Attestations  map[string]string
+	DNSIdentity   string
+	// Namespaces, when set, gives the namespace of each file of a batch,
+	// falling back to Namespace.
+	Namespaces *namespaceMap
+	// MaxSignatureAge, when set, rejects the signature files of a batch
+	// modified longer ago than it.
+	MaxSignatureAge maxAge
+	// ReadAhead is how many files of a batch are read ahead of the one being
+	// verified, 0 to read each one right before verifying it.
+	ReadAhead int
+	// KeyExpiries, by fingerprint, reject the signatures of expired keys.
+	KeyExpiries map[string]keyExpiry
+	// UnicodeForm normalizes the message to "nfc" or "nfd", when set.
+	UnicodeForm string
+	// KeyIDs are the labels of the keys, by fingerprint.
+	KeyIDs map[string]string
+}
+
+// labelKeys labels the keys with the given ids, in order, by fingerprint.
+func labelKeys(pubs []ssh.PublicKey, ids []string) (map[string]string, error) {
+	if len(ids) > len(pubs) {
+		return nil, fmt.Errorf("%d key ids given for %d keys", len(ids), len(pubs))
+	}
+	labels := make(map[string]string, len(ids))
+	for i, id := range ids {
+		labels[ssh.FingerprintSHA256(pubs[i])] = id
+	}
+	return labels, nil
+}
+
+// signedMessage returns the bytes the signature covers, given the message:
+// the message with the same transformations as when it was signed, and the
+// attestations.
+func (v verifier) signedMessage(message, signature []byte) ([]byte, error) {
+	var err error
+	if v.StripBOM {
+		message = stripBOM(message)
+	}
+	if v.CRLF {
+		message = crlfNewlines(message)
+	}
+	message = normalizeUnicode(message, v.UnicodeForm)
+	switch {
+	case v.JSONPointer != "":
+		message, err = jsonPointerSubset(message, v.JSONPointer)
+		if err != nil {
+			return nil, err
+		}
+	case v.JSONCanonical:
+		message, err = canonicalJSON(message)
+		if err != nil {
+			return nil, err
+		}
+	case v.JCS:
+		message, err = jcsJSON(message)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if recorded := attestationHeaders(signature); len(v.Attestations) > 0 || len(recorded) > 0 {
+		if err := diffAttestations(v.Attestations, recorded); err != nil {
+			return nil, err
+		}
+		message = attestedMessage(message, v.Attestations)
+	}
+	return message, nil
+}
+
+// verify verifies the given message against its signature.
+func (v verifier) verify(subject string, message []byte, sigName string, signature []byte) (result, error) {
+	message, err := v.signedMessage(message, signature)
+	if err != nil {
+		return result{}, fmt.Errorf("could not verify %s: %w", subject, err)
+	}
+
+	sig, err := parseSignature(signature)
+	if err != nil {
+		return result{}, fmt.Errorf("could not parse signature %s: %w", sigName, withCode(codeInvalidSignature, err))
+	}
+	if err := v.Policy.check(sig); err != nil {
+		return result{}, fmt.Errorf("could not verify: %w", err)
+	}
+
+	pubs, keyName := v.Pubs, v.KeyName
+	if len(pubs) == 0 {
+		pubs = []ssh.PublicKey{sig.PublicKey}
+		keyName = "embedded in " + sigName
+	}
+
+	pub, i, err := verifyAny(pubs, message, signature, v.Namespace)
+	if err != nil {
+		return result{}, err
+	}
+	if len(pubs) > 1 {
+		keyName = fmt.Sprintf("%s (key %d of %d)", keyName, i+1, len(pubs))
+	}
+
+	if v.DNSIdentity != "" {
+		if err := checkDNSIdentity(v.DNSIdentity, pub); err != nil {
+			return result{}, fmt.Errorf("could not verify DNS identity %s: %w", v.DNSIdentity, withCode(codeDNSIdentity, err))
+		}
+	}
+	if err := checkKeyExpiry(v.KeyExpiries, ssh.FingerprintSHA256(pub), time.Now()); err != nil {
+		return result{}, fmt.Errorf("could not verify: %w", err)
+	}
+
+	return result{
+		File:        subject,
+		Signature:   sigName,
+		Key:         keyName,
+		KeyID:       v.KeyIDs[ssh.FingerprintSHA256(pub)],
+		Fingerprint: ssh.FingerprintSHA256(pub),
+		Namespace:   v.Namespace,
+	}, nil
+}
+
+// verifyAny verifies the message against each of the given keys, returning
+// the first one that matches and its index.
+func verifyAny(pubs []ssh.PublicKey, message, signature []byte, namespace string) (ssh.PublicKey, int, error) {
+	var first error
+	for i, pub := range pubs {
+		err := verifyMessage(pub, message, signature, namespace)
+		if err == nil {
+			return pub, i, nil
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	return nil, -1, first
+}
+
+// contentHashSignature returns the path of the signature of message in a
+// content addressed store: dir/<sha256 of the message>.ssig.
+func contentHashSignature(dir string, message []byte) string {
+	sum := sha256.Sum256(message)
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".ssig")
+}
+
+// readSignature reads the signature of the given file, from sigName,
+// from the file's extended attributes, or from the file name plus ".ssig".
+func readSignature(file, sigName string, xattr bool) (string, []byte, error) {
+	if xattr {
+		if sigName != "" {
+			return "", nil, fmt.Errorf("cannot use a signature path with --xattr")
+		}
+		signature, err := getSignatureXattr(file)
+		if err != nil {
+			return "", nil, fmt.Errorf("could not read signature from %s: %w", file, err)
+		}
+		return "xattr " + signatureXattr, signature, nil
+	}
+
+	if sigName == "" {
+		sigName = file + ".ssig"
+	}
+	signature, err := os.ReadFile(sigName)
+	if err != nil {
+		return "", nil, fmt.Errorf("could not open signature: %w", err)
+	}
+	return sigName, signature, nil
+}
+
+// isUnsigned reports whether the error of [readSignature] is because there
+// is no signature at all, rather than one that could not be read.
+func isUnsigned(err error) bool {
+	return errors.Is(err, fs.ErrNotExist) || errors.Is(err, errXattrMissing)
+}
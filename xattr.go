@@ -0,0 +1,12 @@
+package main
+
+import "errors"
+
+// signatureXattr is the extended attribute used to store signatures when
+// using --xattr.
+const signatureXattr = "user.ssign.signature"
+
+var (
+	errXattrUnsupported = errors.New("extended attributes are not supported on this platform or filesystem")
+	errXattrMissing     = errors.New("no such attribute")
+)
@@ -0,0 +1,6 @@
+package main
+
+import "golang.org/x/sys/unix"
+
+// errnoNoXattr is returned when a file doesn't have the attribute.
+const errnoNoXattr = unix.ENOATTR
@@ -0,0 +1,11 @@
+//go:build !linux && !darwin
+
+package main
+
+func setSignatureXattr(string, []byte) error {
+	return errXattrUnsupported
+}
+
+func getSignatureXattr(string) ([]byte, error) {
+	return nil, errXattrUnsupported
+}
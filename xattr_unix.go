@@ -0,0 +1,40 @@
+//go:build linux || darwin
+
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+func setSignatureXattr(path string, sig []byte) error {
+	if err := unix.Setxattr(path, signatureXattr, sig, 0); err != nil {
+		return xattrError(err)
+	}
+	return nil
+}
+
+func getSignatureXattr(path string) ([]byte, error) {
+	size, err := unix.Getxattr(path, signatureXattr, nil)
+	if err != nil {
+		return nil, xattrError(err)
+	}
+	sig := make([]byte, size)
+	size, err = unix.Getxattr(path, signatureXattr, sig)
+	if err != nil {
+		return nil, xattrError(err)
+	}
+	return sig[:size], nil
+}
+
+func xattrError(err error) error {
+	if errors.Is(err, unix.ENOTSUP) {
+		return errXattrUnsupported
+	}
+	if errors.Is(err, errnoNoXattr) {
+		return fmt.Errorf("xattr %s: %w", signatureXattr, errXattrMissing)
+	}
+	return fmt.Errorf("xattr %s: %w", signatureXattr, err)
+}